@@ -12,11 +12,19 @@ import (
 const (
 	openItem   = "OPEN"
 	closeItem  = "CLOSE"
+	searchItem = "SEARCH"
+	trashItem  = "TRASH"
 	agendaItem = "AGENDA"
 	editItem   = "EDIT"
 	logItem    = "LOGS"
 )
 
+// Selections of the main menu that are not menu items
+const (
+	undoSelection = -1
+	redoSelection = -2
+)
+
 type boxMenu struct {
 	tuiWindow
 	keys      menuKeyMap
@@ -62,6 +70,12 @@ func (m boxMenu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, m.keys.Enter):
 			m.selected = m.cursor
 			return m, tea.Quit
+		case key.Matches(msg, m.keys.Undo):
+			m.selected = undoSelection
+			return m, tea.Quit
+		case key.Matches(msg, m.keys.Redo):
+			m.selected = redoSelection
+			return m, tea.Quit
 		case key.Matches(msg, m.keys.Help):
 			m.help.ShowAll = !m.help.ShowAll
 			return m, nil
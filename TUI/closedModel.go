@@ -5,11 +5,15 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	ctrl "Attimo/control"
+	data "Attimo/database"
 	log "Attimo/logging"
+	"Attimo/timeparse"
 
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
@@ -17,10 +21,14 @@ type closedModel struct {
 	tuiWindow
 
 	keys       selectionKeyMap
-	pointers   []string
+	items      []data.PendingItem
+	rows       []string // Rendered table rows, aligned with items
+	header     string
+	filter     textinput.Model
+	filtered   []int // Indexes into items matching the filter
 	cursor     int
 	startIndex int
-	selected   string
+	selected   *data.PendingItem
 	step       closedStep
 	timeInput  *inputModel
 }
@@ -32,9 +40,7 @@ const (
 	enterTime
 )
 
-const (
-	datetimeFormat = "2006-01-02 15:04:05"
-)
+const previewWidth = 40
 
 func newClosedModel(logger *log.Logger, control *ctrl.Controller) (*closedModel, error) {
 	if logger == nil {
@@ -42,12 +48,12 @@ func newClosedModel(logger *log.Logger, control *ctrl.Controller) (*closedModel,
 	}
 
 	// Get pending items
-	pointers, err := control.GetPendingPointers(logger)
+	items, err := control.GetPendingItems(logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get pending pointers: %v", err)
+		return nil, fmt.Errorf("failed to get pending items: %v", err)
 	}
 
-	if len(pointers) == 0 {
+	if len(items) == 0 {
 		return nil, fmt.Errorf("no pending items to close")
 	}
 
@@ -56,21 +62,113 @@ func newClosedModel(logger *log.Logger, control *ctrl.Controller) (*closedModel,
 		return nil, fmt.Errorf("failed to create time input: %v", err)
 	}
 
+	timeInput.input.Placeholder = "now, -15, yesterday 18:30"
 	timeInput.input.Focus()
 
-	return &closedModel{
+	filter := textinput.New()
+	filter.Placeholder = alluringString
+	filter.Focus()
+	filter.CharLimit = 156
+	filter.Width = 20
+
+	header, rows := pendingTable(items)
+	m := &closedModel{
 		tuiWindow: tuiWindow{
 			logger: logger,
 		},
 		keys:      newSelectionKeyMap(),
-		pointers:  pointers,
+		items:     items,
+		rows:      rows,
+		header:    header,
+		filter:    filter,
 		step:      selectItem,
 		timeInput: timeInput,
-	}, nil
+	}
+	m.applyFilter()
+	return m, nil
+}
+
+// formatElapsed renders a duration in its two largest units, e.g. "3d 4h"
+func formatElapsed(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	days := int(d / (24 * time.Hour))
+	hours := int(d/time.Hour) % 24
+	minutes := int(d/time.Minute) % 60
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("%dm", minutes)
+	}
+}
+
+// truncate shortens s to at most width runes, marking the cut with an ellipsis
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+// pendingTable renders the items as aligned rows below a header
+func pendingTable(items []data.PendingItem) (string, []string) {
+	cells := [][]string{{"Category", "#", "Opened", "Elapsed", "Preview"}}
+	for _, item := range items {
+		opened := "-"
+		elapsed := "-"
+		if !item.Opened.IsZero() {
+			opened = item.Opened.Format(timeparse.ShortLayout)
+			elapsed = formatElapsed(item.Elapsed)
+		}
+		preview := strings.Join(strings.Fields(item.Preview), " ")
+		cells = append(cells, []string{item.Category, strconv.Itoa(item.ID), opened, elapsed, truncate(preview, previewWidth)})
+	}
+
+	widths := make([]int, len(cells[0]))
+	for _, row := range cells {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+
+	lines := make([]string, len(cells))
+	for r, row := range cells {
+		padded := make([]string, len(row))
+		for i, cell := range row {
+			padded[i] = cell + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+		}
+		lines[r] = strings.TrimRight(strings.Join(padded, "  "), " ")
+	}
+	return lines[0], lines[1:]
+}
+
+// applyFilter keeps the items whose category or preview contains the filter text
+func (m *closedModel) applyFilter() {
+	input := strings.ToLower(strings.TrimSpace(m.filter.Value()))
+
+	m.filtered = m.filtered[:0]
+	for i, item := range m.items {
+		if input == "" ||
+			strings.Contains(strings.ToLower(item.Category), input) ||
+			strings.Contains(strings.ToLower(item.Preview), input) {
+			m.filtered = append(m.filtered, i)
+		}
+	}
+
+	if m.cursor >= len(m.filtered) {
+		m.cursor = 0
+		m.startIndex = 0
+	}
 }
 
 func (m *closedModel) Init() tea.Cmd {
-	return nil
+	return textinput.Blink
 }
 
 func (m *closedModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -92,44 +190,25 @@ func (m *closedModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// parseTimeInput reads a close time such as "-15", "yesterday 18:30" or "2024-05-01 9am",
+// an empty input closes now
 func parseTimeInput(input string) (string, error) {
-	input = strings.TrimSpace(input)
 	now := time.Now()
+	if strings.TrimSpace(input) == "" {
+		return timeparse.Format(now), nil
+	}
 
-	switch {
-	case input == "":
-		return now.Format(datetimeFormat), nil
-
-	case strings.HasPrefix(input, "+"):
-		minutesStr := strings.TrimPrefix(input, "+")
-		minutes, err := strconv.Atoi(minutesStr)
-		if err != nil {
-			return "", fmt.Errorf("invalid minutes format: %v", err)
-		}
-
-		return now.Add(time.Duration(minutes) * time.Minute).Format(datetimeFormat), nil
-
-	case strings.HasPrefix(input, "-"):
-		minutesStr := strings.TrimPrefix(input, "-")
-		minutes, err := strconv.Atoi(minutesStr)
-		if err != nil {
-			return "", fmt.Errorf("invalid minutes format: %v", err)
-		}
-
-		return now.Add(-time.Duration(minutes) * time.Minute).Format(datetimeFormat), nil
-
-	default:
-		_, err := time.Parse(datetimeFormat, input)
-		if err != nil {
-			return "", fmt.Errorf("invalid time format: %v", err)
-		}
-		return input, nil
+	t, err := timeparse.Parse(input, now)
+	if err != nil {
+		return "", err
 	}
+	return timeparse.Format(t), nil
 }
 
 func (m *closedModel) handleSelectItemInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch {
-	case key.Matches(msg, m.keys.Quit):
+	// Letters go to the filter, so only esc and the hard quit key leave
+	case key.Matches(msg, m.keys.HardQuit), msg.Type == tea.KeyEsc:
 		m.logger.LogInfo("Quitting close selection")
 		return m, tea.Quit
 
@@ -143,7 +222,7 @@ func (m *closedModel) handleSelectItemInput(msg tea.KeyMsg) (tea.Model, tea.Cmd)
 		return m, nil
 
 	case key.Matches(msg, m.keys.Down):
-		if m.cursor < len(m.pointers)-1 {
+		if m.cursor < len(m.filtered)-1 {
 			m.cursor++
 			if m.cursor >= m.startIndex+maxVisibleItems {
 				m.startIndex = m.cursor - maxVisibleItems + 1
@@ -152,12 +231,18 @@ func (m *closedModel) handleSelectItemInput(msg tea.KeyMsg) (tea.Model, tea.Cmd)
 		return m, nil
 
 	case key.Matches(msg, m.keys.Enter):
-		m.selected = m.pointers[m.cursor]
+		if len(m.filtered) == 0 {
+			return m, nil
+		}
+		m.selected = &m.items[m.filtered[m.cursor]]
 		m.step = enterTime
 		return m, nil
 	}
 
-	return m, nil
+	var cmd tea.Cmd
+	m.filter, cmd = m.filter.Update(msg)
+	m.applyFilter()
+	return m, cmd
 }
 
 // In handleTimeInput method
@@ -201,30 +286,35 @@ func (m *closedModel) View() string {
 func (m *closedModel) viewSelectItem() string {
 	var sb strings.Builder
 	sb.WriteString("Select item to close:\n\n")
+	sb.WriteString(m.filter.View() + "\n\n")
+	sb.WriteString(NOTCURSOR + " " + m.header + "\n")
 
 	// Calculate visible range
 	endIndex := m.startIndex + maxVisibleItems
-	if endIndex > len(m.pointers) {
-		endIndex = len(m.pointers)
+	if endIndex > len(m.filtered) {
+		endIndex = len(m.filtered)
 	}
 
 	// Display visible items
 	for i := m.startIndex; i < endIndex; i++ {
-		cursor := " "
+		cursor := NOTCURSOR
 		if i == m.cursor {
-			cursor = ">"
+			cursor = CURSOR
 		}
-		sb.WriteString(fmt.Sprintf("%s %s\n", cursor, m.pointers[i]))
+		sb.WriteString(fmt.Sprintf("%s %s\n", cursor, m.rows[m.filtered[i]]))
+	}
+	if len(m.filtered) == 0 {
+		sb.WriteString(NOTCURSOR + " No matching items\n")
 	}
 
 	// Add scroll indicators
 	if m.startIndex > 0 {
 		sb.WriteString("\n" + UPCURSOR)
 	}
-	if endIndex < len(m.pointers) {
+	if endIndex < len(m.filtered) {
 		sb.WriteString("\n" + DOWNCURSOR)
 	}
 
-	sb.WriteString("\n\n(↑/↓) navigate • (enter) select • (esc) quit")
+	sb.WriteString("\n\n(type) filter • (↑/↓) navigate • (enter) select • (esc) quit")
 	return sb.String()
 }
@@ -78,12 +78,15 @@ type menuKeyMap struct {
 	Enter key.Binding
 	Up    key.Binding
 	Down  key.Binding
+	Undo  key.Binding
+	Redo  key.Binding
 }
 
 func (k menuKeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Quit, k.HardQuit, k.Help},
 		{k.Enter, k.Up, k.Down},
+		{k.Undo, k.Redo},
 	}
 }
 
@@ -105,6 +108,16 @@ func newMenuKeyMap() menuKeyMap {
 			key.WithKeys("j", "down"),
 			key.WithHelp("↓/j", "move down"),
 		),
+
+		Undo: key.NewBinding(
+			key.WithKeys("ctrl+z"),
+			key.WithHelp("ctrl+z", "undo"),
+		),
+
+		Redo: key.NewBinding(
+			key.WithKeys("ctrl+y"),
+			key.WithHelp("ctrl+y", "redo"),
+		),
 	}
 }
 
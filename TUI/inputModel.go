@@ -3,6 +3,7 @@ package tui
 import (
 	log "Attimo/logging"
 	"fmt"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
@@ -19,6 +20,9 @@ const (
 	StatusError
 )
 
+// maxShownSuggestions is how many completions are listed below an input
+const maxShownSuggestions = 5
+
 type inputModel struct {
 	tuiWindow
 	keys       selectionKeyMap
@@ -28,6 +32,8 @@ type inputModel struct {
 	status     Status
 	statusMsg  string
 	showStatus bool
+
+	suggest func(prefix string) []string // optional source of completions
 }
 
 func newInputModel(prompt string, logger *log.Logger) (*inputModel, error) {
@@ -54,6 +60,22 @@ func newInputModel(prompt string, logger *log.Logger) (*inputModel, error) {
 	}, nil
 }
 
+// withSuggestions completes the input with the candidates returned by suggest,
+// accepted with tab and cycled with the arrow keys
+func (m *inputModel) withSuggestions(suggest func(prefix string) []string) {
+	m.suggest = suggest
+	m.input.ShowSuggestions = true
+	m.refreshSuggestions()
+}
+
+// refreshSuggestions asks for the candidates matching the current value
+func (m *inputModel) refreshSuggestions() {
+	if m.suggest == nil {
+		return
+	}
+	m.input.SetSuggestions(m.suggest(m.input.Value()))
+}
+
 func (m inputModel) Init() tea.Cmd {
 	return textinput.Blink
 }
@@ -82,7 +104,11 @@ func (m inputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+		previous := m.input.Value()
 		m.input, cmd = m.input.Update(msg)
+		if m.input.Value() != previous {
+			m.refreshSuggestions()
+		}
 		return m, cmd
 
 	case tea.WindowSizeMsg:
@@ -111,10 +137,34 @@ func (m inputModel) View() string {
 	}
 
 	return fmt.Sprintf(
-		"%s\n\n%s%s\n\n%s",
+		"%s\n\n%s%s%s\n\n%s",
 		m.prompt,
 		m.input.View(),
+		m.suggestionsView(),
 		statusView,
 		m.help.View(m.keys),
 	)
 }
+
+// suggestionsView lists the first few candidates below the input, highlighting the selected one
+func (m inputModel) suggestionsView() string {
+	suggestions := m.input.AvailableSuggestions()
+	if len(suggestions) == 0 {
+		return ""
+	}
+	if len(suggestions) > maxShownSuggestions {
+		suggestions = suggestions[:maxShownSuggestions]
+	}
+
+	current := m.input.CurrentSuggestion()
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	shown := make([]string, len(suggestions))
+	for i, suggestion := range suggestions {
+		if suggestion == current && m.input.Value() != "" {
+			shown[i] = lipgloss.NewStyle().Bold(true).Render(suggestion)
+		} else {
+			shown[i] = dim.Render(suggestion)
+		}
+	}
+	return "\n" + strings.Join(shown, dim.Render(" · "))
+}
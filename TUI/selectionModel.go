@@ -59,7 +59,7 @@ type selectionModel struct {
 	prompt    string
 	userInput textinput.Model
 	values    []string
-	filtered  []string
+	filtered  []int // Indexes into values matching the filter
 	cursorPos int
 
 	maxWidth   int // Maximum width of any string in values
@@ -96,7 +96,7 @@ func newSelectionModel(prompt string, values []string, logger *log.Logger) (*sel
 		prompt:     prompt,
 		userInput:  ti,
 		values:     values,
-		filtered:   values,
+		filtered:   filterValues(values, ""),
 		cursorPos:  0,
 		startIndex: 0,
 		maxWidth:   maxWidth,
@@ -107,18 +107,14 @@ func (m selectionModel) Init() tea.Cmd {
 	return tea.Batch(tea.ClearScreen, textinput.Blink)
 }
 
-// filterValues excludes values that do not match the input
-func filterValues(values []string, input string) []string {
-	if input == "" {
-		return values
-	}
-
-	var filtered []string
+// filterValues returns the indexes of the values that match the input
+func filterValues(values []string, input string) []int {
+	filtered := make([]int, 0, len(values))
 	lowerInput := strings.ToLower(input)
 
-	for _, value := range values {
+	for i, value := range values {
 		if strings.Contains(strings.ToLower(value), lowerInput) {
-			filtered = append(filtered, value)
+			filtered = append(filtered, i)
 		}
 	}
 	return filtered
@@ -133,8 +129,9 @@ func (m selectionModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 		case key.Matches(msg, m.keys.Enter):
 			if len(m.filtered) > 0 {
-				m.logger.LogInfo("Selected item: %s", m.filtered[m.cursorPos])
-				m.selected = m.cursorPos
+				// Report the index into values, labels may repeat
+				m.logger.LogInfo("Selected item: %s", m.values[m.filtered[m.cursorPos]])
+				m.selected = m.filtered[m.cursorPos]
 				return m, tea.Quit
 			}
 			m.logger.LogInfo("No item to match for: %v", m.userInput.Value())
@@ -206,7 +203,8 @@ func (m selectionModel) View() string {
 	// Display only the visible portion of the list
 	for i := m.startIndex; i < endIndex; i++ {
 		// Pad the string to match maxWidth
-		paddedValue := m.filtered[i] + strings.Repeat(" ", m.maxWidth-utf8.RuneCountInString(m.filtered[i]))
+		value := m.values[m.filtered[i]]
+		paddedValue := value + strings.Repeat(" ", m.maxWidth-utf8.RuneCountInString(value))
 
 		if i == m.cursorPos {
 			sb.WriteString(CURSOR + " " + paddedValue)
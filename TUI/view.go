@@ -2,10 +2,15 @@ package tui
 
 import (
 	ctrl "Attimo/control"
+	data "Attimo/database"
 	log "Attimo/logging"
+	"Attimo/timeparse"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -13,6 +18,9 @@ import (
 const (
 	nilControllerString = "pointer to controller is nil"
 	valuePrompt         = "Enter value for %s:"
+	searchPrompt        = "Search all categories:"
+	trashPrompt         = "Pick an item to restore"
+	trashRetention      = 30 * 24 * time.Hour // age of the items removed by the purge entry
 )
 
 type TUI struct {
@@ -43,85 +51,123 @@ func (tui *TUI) Init(control *ctrl.Controller) error {
 	}
 
 	tui.control = control
-	mainItems := []string{openItem, closeItem, agendaItem, editItem, logItem}
+	mainItems := []string{openItem, closeItem, searchItem, trashItem, agendaItem, editItem, logItem}
 
-	model, err := newBoxModel(tui.logger, mainItems)
-	if err != nil {
-		tui.logger.LogErr("Could not get Main model")
-		return err
-	}
-	p := tea.NewProgram(model)
+	// Come back to the main menu after every command, until the user quits
+	for {
+		model, err := newBoxModel(tui.logger, mainItems)
+		if err != nil {
+			tui.logger.LogErr("Could not get Main model")
+			return err
+		}
+		p := tea.NewProgram(model)
 
-	newModel, err := p.Run()
-	if err != nil {
-		tui.logger.LogErr("tea program ran into an error %v", err)
-		return err
-	}
-	tui.logger.LogInfo("Main model running")
+		newModel, err := p.Run()
+		if err != nil {
+			tui.logger.LogErr("tea program ran into an error %v", err)
+			return err
+		}
+		tui.logger.LogInfo("Main model running")
 
-	if newModel, ok := newModel.(boxMenu); ok {
-		tui.logger.LogInfo("Exited program by picking %v", newModel.selected)
-		var err error
-		switch newModel.selected {
+		newMenu, ok := newModel.(boxMenu)
+		if !ok {
+			tui.logger.LogWarn("Unexpected model return type %v", ok)
+			return nil
+		}
+		if newMenu.selected == nil {
+			return nil
+		}
+
+		tui.logger.LogInfo("Exited program by picking %v", newMenu.selected)
+		message := "Successfully handled command"
+		switch newMenu.selected {
+		case undoSelection:
+			message, err = tui.handleUndo()
+		case redoSelection:
+			message, err = tui.handleRedo()
 		case 0: // OPEN
 			err = tui.handleOpen()
 		case 1: // CLOSE
 			err = tui.handleClose()
+		case 2: // SEARCH
+			err = tui.handleSearch()
+		case 3: // TRASH
+			err = tui.handleTrash()
 		default:
-			tui.logger.LogWarn("Unexpected selection %v", newModel.selected)
+			tui.logger.LogWarn("Unexpected selection %v", newMenu.selected)
+			continue
 		}
 
 		if err != nil {
-			tui.logger.LogErr("Error handling open command: %v", err)
-			communicateError(tui.logger, fmt.Sprintf("Error handling open command: %v", err))
+			tui.logger.LogErr("Error handling command: %v", err)
+			communicateError(tui.logger, fmt.Sprintf("Error handling command: %v", err))
 		} else {
-			tui.logger.LogInfo("Successfully handled command")
-			communicateError(tui.logger, "Successfully handled command")
+			tui.logger.LogInfo("%s", message)
+			communicateError(tui.logger, message)
 		}
-
-	} else {
-		tui.logger.LogWarn("Unexpected model return type %v", ok)
 	}
-
-	return nil
 }
 
-func (tui *TUI) selectCategory() (string, error) {
-	categories, err := tui.control.GetCategories(tui.logger)
+// handleUndo undoes the last change of the session and describes it
+func (tui *TUI) handleUndo() (string, error) {
+	description, err := tui.control.Undo(tui.logger)
+	if errors.Is(err, ctrl.ErrNothingToUndo) {
+		return "Nothing to undo", nil
+	}
 	if err != nil {
-		tui.logger.LogErr("Could not get categories %v", err)
 		return "", err
 	}
+	return "Undid " + description, nil
+}
 
-	model, err := newSelectionModel("Select category", categories, tui.logger)
+// handleRedo redoes the last undone change of the session and describes it
+func (tui *TUI) handleRedo() (string, error) {
+	description, err := tui.control.Redo(tui.logger)
+	if errors.Is(err, ctrl.ErrNothingToRedo) {
+		return "Nothing to redo", nil
+	}
 	if err != nil {
-		tui.logger.LogErr("Could not get selection model %v", err)
 		return "", err
 	}
+	return "Redid " + description, nil
+}
 
-	p := tea.NewProgram(model)
-	newModel, err := p.Run()
+// categoryLabel returns the text shown for a category in the picker
+func categoryLabel(category data.Category) string {
+	label := category.DisplayName
+	if category.Icon != "" {
+		label = category.Icon + " " + label
+	}
+	if category.Description != "" {
+		label += " - " + category.Description
+	}
+	return label
+}
+
+func (tui *TUI) selectCategory() (string, error) {
+	categories, err := tui.control.ListCategories(tui.logger, false)
 	if err != nil {
-		tui.logger.LogErr("tea program ran into an error %v", err)
+		tui.logger.LogErr("Could not get categories %v", err)
 		return "", err
 	}
 
-	tui.logger.LogInfo("Selection model running")
+	labels := make([]string, len(categories))
+	for i, category := range categories {
+		labels[i] = categoryLabel(category)
+	}
 
-	if newModel, ok := newModel.(selectionModel); ok {
-		if newModel.selected == nil {
-			return "", fmt.Errorf("no category selected")
-		}
-		selectedIndex := newModel.selected.(int)
-		if selectedIndex < 0 || selectedIndex >= len(categories) {
-			return "", fmt.Errorf("invalid category index %v", selectedIndex)
-		}
-		selectedCategory := categories[selectedIndex]
-		tui.logger.LogInfo("Selected category: %s", selectedCategory)
-		return selectedCategory, nil
+	selected, err := tui.selectLabel("Select category", labels)
+	if err != nil {
+		tui.logger.LogErr("Could not select category: %v", err)
+		return "", err
+	}
+	if selected < 0 {
+		return "", fmt.Errorf("no category selected")
 	}
 
-	return "", fmt.Errorf("unexpected model return type")
+	selectedCategory := categories[selected].Name
+	tui.logger.LogInfo("Selected category: %s", selectedCategory)
+	return selectedCategory, nil
 }
 
 func (tui *TUI) handleOpen() error {
@@ -146,7 +192,7 @@ func (tui *TUI) handleOpen() error {
 	// collect values through user input
 	values := make(map[string]string)
 	for _, column := range columns {
-		value, err := tui.promptForValue(column)
+		value, err := tui.promptForValidValue(category, column)
 		if err != nil {
 			tui.logger.LogErr("Could not get value for column %s: %v", column, err)
 		}
@@ -162,19 +208,47 @@ func (tui *TUI) handleOpen() error {
 	}
 
 	response := tui.control.OpenItem(tui.logger, request)
-	if response.Success {
+	if !response.Success {
 		return response.Error
 	}
 
 	return nil
 }
 
+// promptForValidValue asks for a column value until it passes validation
+// an empty value is returned as is, leaving the column unset
+func (tui *TUI) promptForValidValue(category, column string) (string, error) {
+	status := ""
+	for {
+		value, err := tui.promptForValue(category, column, status)
+		if err != nil || value == "" {
+			return value, err
+		}
+
+		result := tui.control.ValidateField(tui.logger, category, column, value)
+		if result.IsValid {
+			return value, nil
+		}
+		status = fmt.Sprintf("%s %s", column, result.Message)
+	}
+}
+
 // helper function to get user input
-func (tui *TUI) promptForValue(column string) (string, error) {
+// a non-empty status is shown as an error below the input
+// values are completed from the column's completion rule
+func (tui *TUI) promptForValue(category, column string, status string) (string, error) {
 	model, err := newInputModel(fmt.Sprintf(valuePrompt, column), tui.logger)
 	if err != nil {
 		return "", fmt.Errorf("could not get input model for column %s: %w", column, err)
 	}
+	model.withSuggestions(func(prefix string) []string {
+		// Suggest logs its own errors, an input without completions still works
+		suggestions, _ := tui.control.Suggest(tui.logger, category, column, prefix)
+		return suggestions
+	})
+	if status != "" {
+		model.SetStatus(StatusError, status)
+	}
 
 	p := tea.NewProgram(model)
 	result, err := p.Run()
@@ -215,27 +289,19 @@ func (tui *TUI) handleClose() error {
 	}
 
 	if finalModel, ok := finalModel.(*closedModel); ok {
-		if finalModel.selected != "" && finalModel.timeInput.value != "" {
-			// Split the pointer to get category and ID
-			parts := strings.Split(finalModel.selected, ":")
-			if len(parts) != 2 {
-				return fmt.Errorf("invalid pointer format: %s", finalModel.selected)
-			}
+		if finalModel.selected != nil && finalModel.timeInput.value != "" {
+			category, itemID := finalModel.selected.Category, finalModel.selected.ID
 
-			category := parts[0]
-			itemIDstring := parts[1]
-			itemID, err := strconv.Atoi(itemIDstring)
-			if err != nil {
-				return fmt.Errorf("invalid item ID: %s", itemIDstring)
-			}
-
-			err = tui.control.CloseItem(tui.logger, category, itemID, finalModel.timeInput.value)
+			nextID, err := tui.control.CloseItem(tui.logger, category, itemID, finalModel.timeInput.value)
 			if err != nil {
 				tui.logger.LogErr("Failed to close item: %v", err)
 				return fmt.Errorf("failed to close item: %w", err)
 			}
 
 			tui.logger.LogInfo("Successfully closed item %s in category %s", strconv.Itoa(itemID), category)
+			if nextID != 0 {
+				tui.logger.LogInfo("Scheduled next occurrence as item %d", nextID)
+			}
 		}
 	} else {
 		return fmt.Errorf("unexpected model return type")
@@ -243,3 +309,187 @@ func (tui *TUI) handleClose() error {
 
 	return nil
 }
+
+// searchHitLabel returns the text shown for a search hit in the result list
+func searchHitLabel(hit data.SearchHit) string {
+	snippet := strings.Join(strings.Fields(hit.Snippet), " ")
+	return fmt.Sprintf("%s #%d  %s", hit.Category, hit.ID, snippet)
+}
+
+func (tui *TUI) handleSearch() error {
+	query, err := tui.promptForText(searchPrompt)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(query) == "" {
+		return nil
+	}
+
+	hits, err := tui.control.Search(tui.logger, query)
+	if err != nil {
+		return err
+	}
+
+	if len(hits) == 0 {
+		_, err := tui.promptForText(fmt.Sprintf("Nothing matches %q. Press enter to continue.", query))
+		return err
+	}
+
+	labels := make([]string, len(hits))
+	for i, hit := range hits {
+		labels[i] = searchHitLabel(hit)
+	}
+
+	selected, err := tui.selectLabel(fmt.Sprintf("Results for %q", query), labels)
+	if err != nil || selected < 0 {
+		return err
+	}
+
+	hit := hits[selected]
+	tui.logger.LogInfo("Selected search hit %s:%d", hit.Category, hit.ID)
+	row, err := tui.control.ReadRow(tui.logger, hit.Category, hit.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tui.promptForText(searchHitDetails(hit, row) + "\n\nPress enter to continue.")
+	return err
+}
+
+// searchHitDetails lists the matched text and every value of the hit's row
+func searchHitDetails(hit data.SearchHit, row data.RowData) string {
+	lines := []string{
+		fmt.Sprintf("%s #%d", hit.Category, hit.ID),
+		"Match: " + strings.Join(strings.Fields(hit.Snippet), " "),
+		"",
+	}
+	for _, col := range rowColumns(row) {
+		if value := displayValue(row[col]); value != "" {
+			lines = append(lines, fmt.Sprintf("%s: %s", col, value))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// selectLabel lets the user pick one of labels.
+// It returns the index of the picked label, or -1 when nothing was picked.
+func (tui *TUI) selectLabel(prompt string, labels []string) (int, error) {
+	model, err := newSelectionModel(prompt, labels, tui.logger)
+	if err != nil {
+		return -1, fmt.Errorf("could not get selection model: %w", err)
+	}
+
+	p := tea.NewProgram(model)
+	newModel, err := p.Run()
+	if err != nil {
+		return -1, fmt.Errorf("failed to run selection: %w", err)
+	}
+
+	if newModel, ok := newModel.(selectionModel); ok {
+		if newModel.selected == nil {
+			return -1, nil
+		}
+		selectedIndex := newModel.selected.(int)
+		if selectedIndex < 0 || selectedIndex >= len(labels) {
+			return -1, fmt.Errorf("invalid selection index %v", selectedIndex)
+		}
+		return selectedIndex, nil
+	}
+
+	return -1, fmt.Errorf("unexpected model return type")
+}
+
+// promptForText shows a prompt and returns the text entered by the user
+func (tui *TUI) promptForText(prompt string) (string, error) {
+	model, err := newInputModel(prompt, tui.logger)
+	if err != nil {
+		return "", fmt.Errorf("could not get input model: %w", err)
+	}
+
+	p := tea.NewProgram(model)
+	result, err := p.Run()
+	if err != nil {
+		return "", fmt.Errorf("failed to run input program: %w", err)
+	}
+
+	if m, ok := result.(inputModel); ok {
+		return m.value, nil
+	}
+
+	return "", fmt.Errorf("unexpected model return type")
+}
+
+// rowColumns returns the sorted names of a row's own columns, without the bookkeeping ones
+func rowColumns(row data.RowData) []string {
+	columns := make([]string, 0, len(row))
+	for col := range row {
+		switch col {
+		case "id", "created_at", "updated_at", "deleted_at":
+			continue
+		}
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// displayValue renders a row value as text, empty when it is unset
+func displayValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(timeparse.ShortLayout)
+	case []string:
+		return strings.Join(v, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+// rowPreview returns the values of a row's own columns on a single line
+func rowPreview(row data.RowData) string {
+	columns := rowColumns(row)
+	values := make([]string, 0, len(columns))
+	for _, col := range columns {
+		if text := displayValue(row[col]); text != "" {
+			values = append(values, text)
+		}
+	}
+	return strings.Join(values, " · ")
+}
+
+func (tui *TUI) handleTrash() error {
+	deleted, err := tui.control.ListDeleted(tui.logger, "")
+	if err != nil {
+		return err
+	}
+
+	if len(deleted) == 0 {
+		_, err := tui.promptForText("The trash is empty. Press enter to continue.")
+		return err
+	}
+
+	purgeLabel := fmt.Sprintf("Empty items deleted more than %d days ago", int(trashRetention.Hours()/24))
+	labels := []string{purgeLabel}
+	for _, item := range deleted {
+		labels = append(labels, fmt.Sprintf("%s #%d  deleted %s  %s",
+			item.Category, item.ID, item.DeletedAt.Local().Format(timeparse.ShortLayout), rowPreview(item.Row)))
+	}
+
+	selected, err := tui.selectLabel(trashPrompt, labels)
+	if err != nil || selected < 0 {
+		return err
+	}
+
+	if selected == 0 {
+		purged, err := tui.control.PurgeDeleted(tui.logger, time.Now().Add(-trashRetention))
+		if err != nil {
+			return err
+		}
+		tui.logger.LogInfo("Emptied %d items from the trash", purged)
+		return nil
+	}
+
+	item := deleted[selected-1]
+	return tui.control.RestoreRow(tui.logger, item.Category, item.ID)
+}
@@ -4,7 +4,11 @@ import (
 	"Attimo/database"
 	log "Attimo/logging"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
+	"sort"
+	"time"
 )
 
 func New(data *database.Database, logger *log.Logger) (*Controller, error) {
@@ -79,8 +83,8 @@ func (c *Controller) GetCategoryColumns(logger *log.Logger, category string, con
 			}
 		}
 
-		// get datattpe information for column
-		datatype, err := database.GetDatatypeByName(tx, column)
+		// get datatype information for column, with the category overrides applied
+		datatype, err := database.GetCategoryDatatype(tx, category, column)
 		if err != nil {
 			logger.LogWarn("Failed to get datatype for column %s: %v", column, err)
 			continue
@@ -150,40 +154,117 @@ func (c *Controller) OpenItem(logger *log.Logger, request OpenItemRequest) OpenI
 		validColumns[col] = true
 	}
 
+	// validate every field in one pass, so all problems are reported together
+	fields := make([]string, 0, len(request.Values))
+	for column := range request.Values {
+		fields = append(fields, column)
+	}
+	sort.Strings(fields)
+
 	rowData := make(database.RowData)
-	for column, value := range request.Values {
+	var verrs database.ValidationErrors
+	for _, column := range fields {
+		value := request.Values[column]
 		if !validColumns[column] {
-			return OpenItemResponse{Success: false, Error: fmt.Errorf("invalid column: %s", column)}
+			verrs = append(verrs, &database.ValidationError{
+				Field:   column,
+				Rule:    database.ColumnRule,
+				Value:   value,
+				Message: fmt.Sprintf("is not an open column of category %s", request.Category),
+			})
+			continue
 		}
-		rowData[column] = value
-	}
 
-	for column, value := range rowData {
 		datatype, err := c.GetColumnDatatype(logger, request.Category, column)
 		if err != nil {
 			return OpenItemResponse{Success: false, Error: fmt.Errorf("failed to get datatype for column %s: %w", column, err)}
 		}
 
-		if !datatype.ValidateCheck(value, logger) {
-			return OpenItemResponse{Success: false, Error: fmt.Errorf("invalid value for column %s: %v", column, value)}
+		// parse the text input into the column type before checking it
+		coerced, verr := datatype.ValidateInput(value)
+		if verr != nil {
+			verr.Field = column
+			verrs = append(verrs, verr)
+			continue
 		}
+		rowData[column] = coerced
+	}
+
+	if len(verrs) > 0 {
+		logger.LogWarn("Rejected item for category %s: %v", request.Category, verrs)
+		return OpenItemResponse{Success: false, Error: verrs, Validation: validationResults(verrs)}
 	}
 
 	// create row
-	err = c.CreateRow(logger, request.Category, rowData)
+	_, err = c.CreateRow(logger, request.Category, rowData)
 	if err != nil {
-		return OpenItemResponse{Success: false, Error: fmt.Errorf("failed to create row: %w", err)}
+		response := OpenItemResponse{Success: false, Error: fmt.Errorf("failed to create row: %w", err)}
+		if errors.As(err, &verrs) {
+			response.Validation = validationResults(verrs)
+		}
+		return response
 	}
 
 	return OpenItemResponse{Success: true, Error: nil}
 }
 
-func (c *Controller) CreateRow(logger *log.Logger, category string, values database.RowData) error {
+// CreateRow inserts an item and returns its id. Undoing it moves the item to the trash.
+func (c *Controller) CreateRow(logger *log.Logger, category string, values database.RowData) (int, error) {
+	if logger == nil {
+		return 0, fmt.Errorf(log.LoggerNilString)
+	}
+
+	itemID, err := c.data.CreateRow(category, values)
+	if err != nil {
+		return 0, err
+	}
+
+	c.pushMutation(mutation{
+		description: fmt.Sprintf("create item %d of %s", itemID, category),
+		undo:        func() error { return c.data.DeleteRow(category, itemID) },
+		redo:        func() error { return c.data.RestoreRow(category, itemID) },
+	})
+	return itemID, nil
+}
+
+// UpdateRow changes the values of an item. Undoing it restores the previous values.
+func (c *Controller) UpdateRow(logger *log.Logger, category string, itemID int, values database.RowData) error {
 	if logger == nil {
 		return fmt.Errorf(log.LoggerNilString)
 	}
 
-	return c.data.CreateRow(category, values)
+	if err := c.data.UpdateRow(category, itemID, values); err != nil {
+		logger.LogErr("Failed to update item %d of %s: %v", itemID, category, err)
+		return fmt.Errorf("failed to update item: %w", err)
+	}
+
+	logger.LogInfo("Updated item %d of %s", itemID, category)
+	c.recordRevertible(logger, fmt.Sprintf("update item %d of %s", itemID, category), category, itemID)
+	return nil
+}
+
+// ReadRow returns the values of an item
+func (c *Controller) ReadRow(logger *log.Logger, category string, itemID int) (database.RowData, error) {
+	if logger == nil {
+		return nil, fmt.Errorf(log.LoggerNilString)
+	}
+
+	row, err := c.data.ReadRow(category, itemID)
+	if err != nil {
+		logger.LogErr("Failed to read item %d of %s: %v", itemID, category, err)
+		return nil, fmt.Errorf("failed to read item: %w", err)
+	}
+	return row, nil
+}
+
+// query combines the conditions of the options into a database query.
+// Equality filters are combined with the other conditions.
+func (opts ListRowsOptions) query() database.Query {
+	return database.Query{
+		Where: append(database.EqualFilters(opts.Filters), opts.Where...),
+		AnyOf: opts.AnyOf,
+		Sort:  opts.Sort,
+	}
 }
 
 func (c *Controller) ListRows(logger *log.Logger, opts ListRowsOptions) (*ListRowsResult, error) {
@@ -194,6 +275,10 @@ func (c *Controller) ListRows(logger *log.Logger, opts ListRowsOptions) (*ListRo
 	if opts.Category == "" {
 		return nil, fmt.Errorf("category is empty")
 	}
+	// Uncounted listings only move forward from a cursor, they cannot skip to a page
+	if opts.SkipTotal && opts.Cursor == "" && opts.Page > 1 {
+		return nil, fmt.Errorf("page %d needs a cursor when rows are not counted", opts.Page)
+	}
 
 	// Set defaults if not provided
 	if opts.Page < 1 {
@@ -202,12 +287,27 @@ func (c *Controller) ListRows(logger *log.Logger, opts ListRowsOptions) (*ListRo
 	if opts.PageSize < 1 {
 		opts.PageSize = DefaultPageSize
 	}
-	if opts.Filters == nil {
-		opts.Filters = database.RowData{}
+	query := opts.query()
+
+	// Without counting, rows are fetched from a cursor instead of an offset
+	if opts.Cursor != "" || opts.SkipTotal {
+		rows, next, err := c.data.ListRowsAfter(opts.Category, query, opts.Cursor, opts.PageSize)
+		if err != nil {
+			logger.LogErr("Failed to list rows for category %s: %v", opts.Category, err)
+			return nil, fmt.Errorf("failed to list rows: %w", err)
+		}
+
+		logger.LogInfo("Listed %d rows for category %s", len(rows), opts.Category)
+		return &ListRowsResult{
+			Rows:        rows,
+			CurrentPage: opts.Page,
+			PageSize:    opts.PageSize,
+			NextCursor:  next,
+		}, nil
 	}
 
 	// Get rows from database with pagination
-	rows, total, err := c.data.ListRows(opts.Category, opts.Filters, opts.Page, opts.PageSize)
+	rows, total, err := c.data.ListRowsQuery(opts.Category, query, opts.Page, opts.PageSize)
 	if err != nil {
 		logger.LogErr("Failed to list rows for category %s: %v", opts.Category, err)
 		return nil, fmt.Errorf("failed to list rows: %w", err)
@@ -241,7 +341,7 @@ func (c *Controller) GetColumnDatatype(logger *log.Logger, category, column stri
 	}
 	defer tx.Rollback()
 
-	datatype, err := database.GetDatatypeByName(tx, column)
+	datatype, err := database.GetCategoryDatatype(tx, category, column)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get datatype: %w", err)
 	}
@@ -253,10 +353,512 @@ func (c *Controller) BeginTransaction() (*sql.Tx, error) {
 	return c.data.DB.Begin()
 }
 
-func (c *Controller) CloseItem(logger *log.Logger, category string, itemID int, closeDate string) error {
-	return c.data.CloseItem(category, itemID, closeDate)
+// CloseItem closes a pending item and returns the id of its next occurrence, or 0 when it does not recur.
+// Undoing it reopens the item, tracks it as pending again and moves the next occurrence to the trash.
+func (c *Controller) CloseItem(logger *log.Logger, category string, itemID int, closeDate string) (int, error) {
+	if logger == nil {
+		return 0, fmt.Errorf(log.LoggerNilString)
+	}
+
+	nextID, err := c.data.CloseItem(category, itemID, closeDate)
+	if err != nil {
+		return 0, err
+	}
+
+	description := fmt.Sprintf("close item %d of %s", itemID, category)
+	if nextID == 0 {
+		c.recordRevertible(logger, description, category, itemID)
+		return 0, nil
+	}
+
+	logger.LogInfo("Created item %d of %s as the next occurrence of item %d", nextID, category, itemID)
+	m, err := c.revertibleMutation(description, category, itemID)
+	if err != nil {
+		logger.LogWarn("Cannot undo %s: %v", description, err)
+		return nextID, nil
+	}
+	undoClose, redoClose := m.undo, m.redo
+	m.undo = func() error {
+		if err := c.data.DeleteRow(category, nextID); err != nil {
+			return err
+		}
+		return undoClose()
+	}
+	m.redo = func() error {
+		if err := redoClose(); err != nil {
+			return err
+		}
+		return c.data.RestoreRow(category, nextID)
+	}
+	c.pushMutation(m)
+	return nextID, nil
+}
+
+// StopRecurrence ends the series of a recurring item. Undoing it makes the item recur again.
+func (c *Controller) StopRecurrence(logger *log.Logger, category string, itemID int) error {
+	if logger == nil {
+		return fmt.Errorf(log.LoggerNilString)
+	}
+
+	if err := c.data.StopRecurrence(category, itemID); err != nil {
+		logger.LogErr("Failed to stop recurrence of item %d of %s: %v", itemID, category, err)
+		return fmt.Errorf("failed to stop recurrence: %w", err)
+	}
+
+	logger.LogInfo("Stopped recurrence of item %d of %s", itemID, category)
+	c.recordRevertible(logger, fmt.Sprintf("stop recurrence of item %d of %s", itemID, category), category, itemID)
+	return nil
 }
 
 func (c *Controller) GetPendingPointers(logger *log.Logger) ([]string, error) {
 	return c.data.GetPendingPointers()
 }
+
+// GetPendingItems returns the open items with their details, oldest first.
+func (c *Controller) GetPendingItems(logger *log.Logger) ([]database.PendingItem, error) {
+	if logger == nil {
+		return nil, fmt.Errorf(log.LoggerNilString)
+	}
+
+	items, err := c.data.GetPendingItems()
+	if err != nil {
+		logger.LogErr("Failed to get pending items: %v", err)
+		return nil, fmt.Errorf("failed to get pending items: %w", err)
+	}
+	return items, nil
+}
+
+func (c *Controller) CreateCategory(logger *log.Logger, template database.CategoryTemplate) error {
+	if logger == nil {
+		return fmt.Errorf(log.LoggerNilString)
+	}
+
+	if err := c.data.CreateCategory(template); err != nil {
+		logger.LogErr("Failed to create category %s: %v", template.Name, err)
+		return fmt.Errorf("failed to create category: %w", err)
+	}
+
+	logger.LogInfo("Created category %s", template.Name)
+	return nil
+}
+
+func (c *Controller) DeleteCategory(logger *log.Logger, category string) error {
+	if logger == nil {
+		return fmt.Errorf(log.LoggerNilString)
+	}
+
+	if err := c.data.DeleteCategory(category); err != nil {
+		logger.LogErr("Failed to delete category %s: %v", category, err)
+		return fmt.Errorf("failed to delete category: %w", err)
+	}
+
+	logger.LogInfo("Deleted category %s", category)
+	return nil
+}
+
+func (c *Controller) AddColumn(logger *log.Logger, category, datatype string) error {
+	if logger == nil {
+		return fmt.Errorf(log.LoggerNilString)
+	}
+
+	if err := c.data.AddColumn(category, datatype); err != nil {
+		logger.LogErr("Failed to add column %s to %s: %v", datatype, category, err)
+		return fmt.Errorf("failed to add column: %w", err)
+	}
+
+	logger.LogInfo("Added column %s to category %s", datatype, category)
+	return nil
+}
+
+func (c *Controller) RemoveColumn(logger *log.Logger, category, column string) error {
+	if logger == nil {
+		return fmt.Errorf(log.LoggerNilString)
+	}
+
+	if err := c.data.RemoveColumn(category, column); err != nil {
+		logger.LogErr("Failed to remove column %s from %s: %v", column, category, err)
+		return fmt.Errorf("failed to remove column: %w", err)
+	}
+
+	logger.LogInfo("Removed column %s from category %s", column, category)
+	return nil
+}
+
+func (c *Controller) CreateDatatype(logger *log.Logger, datatype database.Datatype) (int, error) {
+	if logger == nil {
+		return 0, fmt.Errorf(log.LoggerNilString)
+	}
+
+	id, err := c.data.CreateDatatype(datatype)
+	if err != nil {
+		logger.LogErr("Failed to create datatype %s: %v", datatype.Name, err)
+		return 0, fmt.Errorf("failed to create datatype: %w", err)
+	}
+
+	logger.LogInfo("Created datatype %s with id %d", datatype.Name, id)
+	return id, nil
+}
+
+func (c *Controller) UpdateDatatype(logger *log.Logger, datatype database.Datatype) error {
+	if logger == nil {
+		return fmt.Errorf(log.LoggerNilString)
+	}
+
+	if err := c.data.UpdateDatatype(datatype); err != nil {
+		logger.LogErr("Failed to update datatype %s: %v", datatype.Name, err)
+		return fmt.Errorf("failed to update datatype: %w", err)
+	}
+
+	logger.LogInfo("Updated datatype %s", datatype.Name)
+	return nil
+}
+
+func (c *Controller) DeleteDatatype(logger *log.Logger, name string) error {
+	if logger == nil {
+		return fmt.Errorf(log.LoggerNilString)
+	}
+
+	if err := c.data.DeleteDatatype(name); err != nil {
+		logger.LogErr("Failed to delete datatype %s: %v", name, err)
+		return fmt.Errorf("failed to delete datatype: %w", err)
+	}
+
+	logger.LogInfo("Deleted datatype %s", name)
+	return nil
+}
+
+func (c *Controller) ListCategories(logger *log.Logger, includeArchived bool) ([]database.Category, error) {
+	if logger == nil {
+		return nil, fmt.Errorf(log.LoggerNilString)
+	}
+
+	return c.data.ListCategories(includeArchived)
+}
+
+func (c *Controller) UpdateCategoryDetails(logger *log.Logger, category database.Category) error {
+	if logger == nil {
+		return fmt.Errorf(log.LoggerNilString)
+	}
+
+	if err := c.data.UpdateCategoryDetails(category); err != nil {
+		logger.LogErr("Failed to update category %s: %v", category.Name, err)
+		return fmt.Errorf("failed to update category: %w", err)
+	}
+
+	logger.LogInfo("Updated details of category %s", category.Name)
+	return nil
+}
+
+func (c *Controller) ArchiveCategory(logger *log.Logger, category string) error {
+	if logger == nil {
+		return fmt.Errorf(log.LoggerNilString)
+	}
+
+	if err := c.data.ArchiveCategory(category); err != nil {
+		logger.LogErr("Failed to archive category %s: %v", category, err)
+		return fmt.Errorf("failed to archive category: %w", err)
+	}
+
+	logger.LogInfo("Archived category %s", category)
+	return nil
+}
+
+func (c *Controller) RestoreCategory(logger *log.Logger, category string) error {
+	if logger == nil {
+		return fmt.Errorf(log.LoggerNilString)
+	}
+
+	if err := c.data.RestoreCategory(category); err != nil {
+		logger.LogErr("Failed to restore category %s: %v", category, err)
+		return fmt.Errorf("failed to restore category: %w", err)
+	}
+
+	logger.LogInfo("Restored category %s", category)
+	return nil
+}
+
+func (c *Controller) SetDatatypeOverride(logger *log.Logger, override database.DatatypeOverride) error {
+	if logger == nil {
+		return fmt.Errorf(log.LoggerNilString)
+	}
+
+	if err := c.data.SetDatatypeOverride(override); err != nil {
+		logger.LogErr("Failed to override %s in %s: %v", override.Datatype, override.Category, err)
+		return fmt.Errorf("failed to set datatype override: %w", err)
+	}
+
+	logger.LogInfo("Set override of %s in category %s", override.Datatype, override.Category)
+	return nil
+}
+
+func (c *Controller) ClearDatatypeOverride(logger *log.Logger, category, datatype string) error {
+	if logger == nil {
+		return fmt.Errorf(log.LoggerNilString)
+	}
+
+	if err := c.data.ClearDatatypeOverride(category, datatype); err != nil {
+		logger.LogErr("Failed to clear override of %s in %s: %v", datatype, category, err)
+		return fmt.Errorf("failed to clear datatype override: %w", err)
+	}
+
+	logger.LogInfo("Cleared override of %s in category %s", datatype, category)
+	return nil
+}
+
+// validationResults converts database validation errors into controller results
+func validationResults(verrs database.ValidationErrors) []ValidationResult {
+	results := make([]ValidationResult, len(verrs))
+	for i, verr := range verrs {
+		results[i] = ValidationResult{
+			Field:   verr.Field,
+			Rule:    verr.Rule,
+			IsValid: false,
+			Message: verr.Message,
+		}
+	}
+	return results
+}
+
+// ValidateField checks a single text input against the datatype of a column,
+// so that interfaces can report problems while the user is still typing
+func (c *Controller) ValidateField(logger *log.Logger, category, column, value string) ValidationResult {
+	if logger == nil {
+		return ValidationResult{Field: column, IsValid: false, Message: log.LoggerNilString}
+	}
+
+	datatype, err := c.GetColumnDatatype(logger, category, column)
+	if err != nil {
+		return ValidationResult{Field: column, IsValid: false, Message: err.Error()}
+	}
+
+	if _, verr := datatype.ValidateInput(value); verr != nil {
+		return ValidationResult{Field: column, Rule: verr.Rule, IsValid: false, Message: verr.Message}
+	}
+
+	return ValidationResult{Field: column, IsValid: true}
+}
+
+// Search finds the items of every category containing all the words of query, best matches first
+func (c *Controller) Search(logger *log.Logger, query string) ([]database.SearchHit, error) {
+	if logger == nil {
+		return nil, fmt.Errorf(log.LoggerNilString)
+	}
+
+	hits, err := c.data.Search(query, DefaultSearchLimit)
+	if err != nil {
+		logger.LogErr("Failed to search for %q: %v", query, err)
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+
+	logger.LogInfo("Search for %q found %d items", query, len(hits))
+	return hits, nil
+}
+
+// Suggest returns completion candidates for a column value starting with prefix
+func (c *Controller) Suggest(logger *log.Logger, category, column, prefix string) ([]string, error) {
+	if logger == nil {
+		return nil, fmt.Errorf(log.LoggerNilString)
+	}
+
+	suggestions, err := c.data.Suggest(category, column, prefix, DefaultSuggestionLimit)
+	if err != nil {
+		logger.LogErr("Failed to suggest values for %s.%s: %v", category, column, err)
+		return nil, fmt.Errorf("failed to suggest values: %w", err)
+	}
+	return suggestions, nil
+}
+
+// ExportCSV writes every row matching the options as CSV. Paging options are ignored.
+func (c *Controller) ExportCSV(logger *log.Logger, opts ListRowsOptions, w io.Writer) error {
+	if logger == nil {
+		return fmt.Errorf(log.LoggerNilString)
+	}
+
+	if err := c.data.ExportCSV(opts.Category, opts.query(), w); err != nil {
+		logger.LogErr("Failed to export category %s: %v", opts.Category, err)
+		return fmt.Errorf("failed to export rows: %w", err)
+	}
+
+	logger.LogInfo("Exported category %s", opts.Category)
+	return nil
+}
+
+// ListDeleted returns the items in the trash of a category, or of every category when category is empty
+func (c *Controller) ListDeleted(logger *log.Logger, category string) ([]database.DeletedRow, error) {
+	if logger == nil {
+		return nil, fmt.Errorf(log.LoggerNilString)
+	}
+
+	deleted, err := c.data.ListDeleted(category)
+	if err != nil {
+		logger.LogErr("Failed to list deleted items: %v", err)
+		return nil, fmt.Errorf("failed to list deleted items: %w", err)
+	}
+	return deleted, nil
+}
+
+func (c *Controller) RestoreRow(logger *log.Logger, category string, itemID int) error {
+	if logger == nil {
+		return fmt.Errorf(log.LoggerNilString)
+	}
+
+	if err := c.data.RestoreRow(category, itemID); err != nil {
+		logger.LogErr("Failed to restore item %d of %s: %v", itemID, category, err)
+		return fmt.Errorf("failed to restore item: %w", err)
+	}
+
+	logger.LogInfo("Restored item %d of %s", itemID, category)
+	return nil
+}
+
+// PurgeDeleted permanently removes the items deleted before olderThan, returning how many were removed
+func (c *Controller) PurgeDeleted(logger *log.Logger, olderThan time.Time) (int, error) {
+	if logger == nil {
+		return 0, fmt.Errorf(log.LoggerNilString)
+	}
+
+	purged, err := c.data.PurgeDeleted(olderThan)
+	if err != nil {
+		logger.LogErr("Failed to purge deleted items: %v", err)
+		return 0, fmt.Errorf("failed to purge deleted items: %w", err)
+	}
+
+	logger.LogInfo("Purged %d items deleted before %v", purged, olderThan)
+	return purged, nil
+}
+
+// RowHistory returns the recorded changes of an item, oldest first
+func (c *Controller) RowHistory(logger *log.Logger, category string, itemID int) ([]database.HistoryEntry, error) {
+	if logger == nil {
+		return nil, fmt.Errorf(log.LoggerNilString)
+	}
+
+	entries, err := c.data.RowHistory(category, itemID)
+	if err != nil {
+		logger.LogErr("Failed to get history of item %d of %s: %v", itemID, category, err)
+		return nil, fmt.Errorf("failed to get history: %w", err)
+	}
+	return entries, nil
+}
+
+// RevertRow restores the values an item had before the change recorded by historyID
+func (c *Controller) RevertRow(logger *log.Logger, category string, itemID int, historyID int) error {
+	if logger == nil {
+		return fmt.Errorf(log.LoggerNilString)
+	}
+
+	if err := c.data.RevertRow(category, itemID, historyID); err != nil {
+		logger.LogErr("Failed to revert item %d of %s: %v", itemID, category, err)
+		return fmt.Errorf("failed to revert item: %w", err)
+	}
+
+	logger.LogInfo("Reverted item %d of %s to before change %d", itemID, category, historyID)
+	return nil
+}
+
+// Doctor checks the pending items, category tables and datatypes for inconsistencies.
+// With fix set, the repairable problems are fixed in a single transaction.
+func (c *Controller) Doctor(logger *log.Logger, fix bool) (database.DoctorReport, error) {
+	if logger == nil {
+		return database.DoctorReport{}, fmt.Errorf(log.LoggerNilString)
+	}
+
+	report, err := c.data.Doctor(fix)
+	if err != nil {
+		logger.LogErr("Failed to check database consistency: %v", err)
+		return database.DoctorReport{}, fmt.Errorf("failed to check database consistency: %w", err)
+	}
+
+	logger.LogInfo("Doctor found %d problems and fixed %d", len(report.Problems), report.Fixed())
+	return report, nil
+}
+
+// hasLink reports whether the relation from source to target already exists
+func (c *Controller) hasLink(source, target database.ItemRef, relation string) (bool, error) {
+	links, err := c.data.LinksOf(source)
+	if err != nil {
+		return false, err
+	}
+	for _, link := range links {
+		if link.Source == source && link.Target == target && link.Relation == relation {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Link relates the source item to the target item. Undoing it removes the link again.
+func (c *Controller) Link(logger *log.Logger, source, target database.ItemRef, relation string) error {
+	if logger == nil {
+		return fmt.Errorf(log.LoggerNilString)
+	}
+
+	existed, err := c.hasLink(source, target, relation)
+	if err != nil {
+		logger.LogErr("Failed to read links of %s: %v", source, err)
+		return fmt.Errorf("failed to link items: %w", err)
+	}
+
+	if err := c.data.Link(source, target, relation); err != nil {
+		logger.LogErr("Failed to link %s to %s: %v", source, target, err)
+		return fmt.Errorf("failed to link items: %w", err)
+	}
+
+	logger.LogInfo("Linked %s %s %s", source, relation, target)
+	if !existed {
+		c.pushMutation(mutation{
+			description: fmt.Sprintf("link %s to %s", source, target),
+			undo:        func() error { return c.data.Unlink(source, target, relation) },
+			redo:        func() error { return c.data.Link(source, target, relation) },
+		})
+	}
+	return nil
+}
+
+// Unlink removes the relation from the source item to the target item. Undoing it links them again.
+func (c *Controller) Unlink(logger *log.Logger, source, target database.ItemRef, relation string) error {
+	if logger == nil {
+		return fmt.Errorf(log.LoggerNilString)
+	}
+
+	if err := c.data.Unlink(source, target, relation); err != nil {
+		logger.LogErr("Failed to unlink %s from %s: %v", source, target, err)
+		return fmt.Errorf("failed to unlink items: %w", err)
+	}
+
+	logger.LogInfo("Unlinked %s %s %s", source, relation, target)
+	c.pushMutation(mutation{
+		description: fmt.Sprintf("unlink %s from %s", source, target),
+		undo:        func() error { return c.data.Link(source, target, relation) },
+		redo:        func() error { return c.data.Unlink(source, target, relation) },
+	})
+	return nil
+}
+
+// LinksOf returns the links from or to an item, oldest first
+func (c *Controller) LinksOf(logger *log.Logger, item database.ItemRef) ([]database.Link, error) {
+	if logger == nil {
+		return nil, fmt.Errorf(log.LoggerNilString)
+	}
+
+	links, err := c.data.LinksOf(item)
+	if err != nil {
+		logger.LogErr("Failed to get links of %s: %v", item, err)
+		return nil, fmt.Errorf("failed to get links: %w", err)
+	}
+	return links, nil
+}
+
+// BlockedItems returns the open items that depend on items which are still open
+func (c *Controller) BlockedItems(logger *log.Logger) ([]database.BlockedItem, error) {
+	if logger == nil {
+		return nil, fmt.Errorf(log.LoggerNilString)
+	}
+
+	blocked, err := c.data.BlockedItems()
+	if err != nil {
+		logger.LogErr("Failed to get blocked items: %v", err)
+		return nil, fmt.Errorf("failed to get blocked items: %w", err)
+	}
+	return blocked, nil
+}
@@ -0,0 +1,37 @@
+package control
+
+import (
+	"Attimo/database"
+	"testing"
+)
+
+func TestListRowsSkipTotal(t *testing.T) {
+	c, logger := setupTestController(t)
+
+	for _, text := range []string{"first", "second", "third"} {
+		if _, err := c.CreateRow(logger, "Habits", database.RowData{"Opened": "2024-05-01 09:00", "Note": text}); err != nil {
+			t.Fatalf("CreateRow() error = %v", err)
+		}
+	}
+
+	first, err := c.ListRows(logger, ListRowsOptions{Category: "Habits", PageSize: 2, SkipTotal: true})
+	if err != nil {
+		t.Fatalf("ListRows() error = %v", err)
+	}
+	if len(first.Rows) != 2 || first.NextCursor == "" || first.TotalRows != 0 {
+		t.Fatalf("ListRows() = %+v, want two uncounted rows and a cursor", first)
+	}
+
+	// Without a cursor a later page would silently list the first one again
+	if _, err := c.ListRows(logger, ListRowsOptions{Category: "Habits", PageSize: 2, Page: 2, SkipTotal: true}); err == nil {
+		t.Errorf("ListRows() of page 2 without a cursor succeeded")
+	}
+
+	next, err := c.ListRows(logger, ListRowsOptions{Category: "Habits", PageSize: 2, Page: 2, SkipTotal: true, Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatalf("ListRows() with a cursor error = %v", err)
+	}
+	if len(next.Rows) != 1 || next.NextCursor != "" {
+		t.Errorf("ListRows() with a cursor = %+v, want the last row", next)
+	}
+}
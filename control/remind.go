@@ -0,0 +1,135 @@
+package control
+
+import (
+	"Attimo/database"
+	log "Attimo/logging"
+	"Attimo/timeparse"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultReminderWindow is how far ahead Remind looks for upcoming deadlines
+const DefaultReminderWindow = 24 * time.Hour
+
+// ReminderHookEnv names the environment variable holding the default reminder hook command
+const ReminderHookEnv = "ATTIMO_REMIND_HOOK"
+
+// Reminder states
+const (
+	ReminderOverdue  = "overdue"
+	ReminderUpcoming = "upcoming"
+)
+
+// Upcoming returns the open items due within window from now, earliest first
+func (c *Controller) Upcoming(logger *log.Logger, window time.Duration) ([]database.DueItem, error) {
+	if logger == nil {
+		return nil, fmt.Errorf(log.LoggerNilString)
+	}
+
+	now := time.Now()
+	items, err := c.data.DueItems(now, now.Add(window))
+	if err != nil {
+		logger.LogErr("Failed to get upcoming items: %v", err)
+		return nil, fmt.Errorf("failed to get upcoming items: %w", err)
+	}
+	return items, nil
+}
+
+// Overdue returns the open items whose deadline has passed, earliest first
+func (c *Controller) Overdue(logger *log.Logger) ([]database.DueItem, error) {
+	if logger == nil {
+		return nil, fmt.Errorf(log.LoggerNilString)
+	}
+
+	items, err := c.data.DueItems(time.Time{}, time.Now())
+	if err != nil {
+		logger.LogErr("Failed to get overdue items: %v", err)
+		return nil, fmt.Errorf("failed to get overdue items: %w", err)
+	}
+	return items, nil
+}
+
+// reminderLine describes a due item on a single line
+func reminderLine(state string, item database.DueItem) string {
+	line := fmt.Sprintf("%-8s  %s  %s #%d", strings.ToUpper(state), item.Deadline.Format(timeparse.ShortLayout), item.Category, item.ID)
+	if preview := strings.Join(strings.Fields(item.Preview), " "); preview != "" {
+		line += "  " + preview
+	}
+	return line
+}
+
+// runReminderHook runs the hook through the shell with the item in its environment and the line on stdin.
+// The output of the hook goes to out.
+func runReminderHook(hook, state string, item database.DueItem, line string, out io.Writer) error {
+	cmd := exec.Command("sh", "-c", hook)
+	cmd.Env = append(os.Environ(),
+		"ATTIMO_STATE="+state,
+		"ATTIMO_CATEGORY="+item.Category,
+		"ATTIMO_ID="+strconv.Itoa(item.ID),
+		"ATTIMO_DEADLINE="+item.Deadline.Format(time.RFC3339),
+		"ATTIMO_PREVIEW="+item.Preview,
+	)
+	cmd.Stdin = strings.NewReader(line + "\n")
+	cmd.Stdout = out
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// Remind emits the overdue items and those due within window, for use from cron.
+// Without a hook each item is written to out as a line; otherwise the hook command
+// runs once per item with ATTIMO_STATE, ATTIMO_CATEGORY, ATTIMO_ID, ATTIMO_DEADLINE
+// and ATTIMO_PREVIEW set, the line on stdin and its output to out. It returns the number of items.
+func (c *Controller) Remind(logger *log.Logger, window time.Duration, hook string, out io.Writer) (int, error) {
+	if logger == nil {
+		return 0, fmt.Errorf(log.LoggerNilString)
+	}
+
+	overdue, err := c.Overdue(logger)
+	if err != nil {
+		return 0, err
+	}
+	upcoming, err := c.Upcoming(logger, window)
+	if err != nil {
+		return 0, err
+	}
+
+	groups := []struct {
+		state string
+		items []database.DueItem
+	}{
+		{ReminderOverdue, overdue},
+		{ReminderUpcoming, upcoming},
+	}
+
+	count := 0
+	var failed []string
+	for _, group := range groups {
+		for _, item := range group.items {
+			count++
+			line := reminderLine(group.state, item)
+			if hook == "" {
+				if _, err := fmt.Fprintln(out, line); err != nil {
+					return count, fmt.Errorf("failed to write reminder: %w", err)
+				}
+				continue
+			}
+
+			// A failing hook must not hide the remaining reminders
+			if err := runReminderHook(hook, group.state, item, line, out); err != nil {
+				logger.LogErr("Reminder hook failed for %s #%d: %v", item.Category, item.ID, err)
+				failed = append(failed, fmt.Sprintf("%s #%d", item.Category, item.ID))
+			}
+		}
+	}
+
+	logger.LogInfo("Sent %d reminders", count)
+	if len(failed) > 0 {
+		return count, fmt.Errorf("reminder hook failed for %s", strings.Join(failed, ", "))
+	}
+	return count, nil
+}
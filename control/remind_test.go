@@ -0,0 +1,109 @@
+package control
+
+import (
+	"Attimo/database"
+	log "Attimo/logging"
+	"Attimo/timeparse"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupDueItems creates an overdue, an upcoming, a later and a closed overdue item.
+// It returns the ids of the overdue and the upcoming item.
+func setupDueItems(t *testing.T, c *Controller, logger *log.Logger) (int, int) {
+	t.Helper()
+
+	now := time.Now()
+	deadlines := []time.Duration{-2 * time.Hour, 2 * time.Hour, 48 * time.Hour, -3 * time.Hour}
+	ids := make([]int, len(deadlines))
+	for i, offset := range deadlines {
+		id, err := c.CreateRow(logger, "Habits", database.RowData{
+			"Opened":   timeparse.Format(now.Add(-24 * time.Hour)),
+			"Deadline": timeparse.Format(now.Add(offset)),
+			"Note":     fmt.Sprintf("item %d", i),
+		})
+		if err != nil {
+			t.Fatalf("CreateRow() error = %v", err)
+		}
+		ids[i] = id
+	}
+	if _, err := c.CloseItem(logger, "Habits", ids[3], timeparse.Format(now)); err != nil {
+		t.Fatalf("CloseItem() error = %v", err)
+	}
+	return ids[0], ids[1]
+}
+
+func TestUpcomingAndOverdue(t *testing.T) {
+	c, logger := setupTestController(t)
+	overdueID, upcomingID := setupDueItems(t, c, logger)
+
+	overdue, err := c.Overdue(logger)
+	if err != nil {
+		t.Fatalf("Overdue() error = %v", err)
+	}
+	if len(overdue) != 1 || overdue[0].ID != overdueID {
+		t.Errorf("Overdue() = %+v, want only item %d", overdue, overdueID)
+	}
+
+	upcoming, err := c.Upcoming(logger, DefaultReminderWindow)
+	if err != nil {
+		t.Fatalf("Upcoming() error = %v", err)
+	}
+	if len(upcoming) != 1 || upcoming[0].ID != upcomingID {
+		t.Errorf("Upcoming() = %+v, want only item %d", upcoming, upcomingID)
+	}
+
+	if _, err := c.Overdue(nil); err == nil {
+		t.Errorf("Overdue() without a logger succeeded")
+	}
+	if _, err := c.Upcoming(nil, DefaultReminderWindow); err == nil {
+		t.Errorf("Upcoming() without a logger succeeded")
+	}
+}
+
+func TestRemind(t *testing.T) {
+	c, logger := setupTestController(t)
+	overdueID, upcomingID := setupDueItems(t, c, logger)
+
+	var out bytes.Buffer
+	count, err := c.Remind(logger, DefaultReminderWindow, "", &out)
+	if err != nil {
+		t.Fatalf("Remind() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if count != 2 || len(lines) != 2 {
+		t.Fatalf("Remind() = %d items, output %q, want 2 lines", count, out.String())
+	}
+	if !strings.HasPrefix(lines[0], "OVERDUE") || !strings.Contains(lines[0], fmt.Sprintf("Habits #%d", overdueID)) {
+		t.Errorf("first line = %q, want the overdue item %d", lines[0], overdueID)
+	}
+	if !strings.HasPrefix(lines[1], "UPCOMING") || !strings.Contains(lines[1], fmt.Sprintf("Habits #%d", upcomingID)) {
+		t.Errorf("second line = %q, want the upcoming item %d", lines[1], upcomingID)
+	}
+}
+
+func TestRemindHook(t *testing.T) {
+	c, logger := setupTestController(t)
+	overdueID, upcomingID := setupDueItems(t, c, logger)
+
+	// The hook reads the line on stdin and the item from its environment, its output goes to out
+	var out bytes.Buffer
+	count, err := c.Remind(logger, DefaultReminderWindow, `read line; echo "$ATTIMO_STATE $ATTIMO_ID"`, &out)
+	if err != nil {
+		t.Fatalf("Remind() error = %v", err)
+	}
+	want := fmt.Sprintf("%s %d\n%s %d\n", ReminderOverdue, overdueID, ReminderUpcoming, upcomingID)
+	if count != 2 || out.String() != want {
+		t.Errorf("Remind() = %d items, output %q, want 2 and %q", count, out.String(), want)
+	}
+
+	// A failing hook still runs for every item
+	count, err = c.Remind(logger, DefaultReminderWindow, "exit 1", &out)
+	if err == nil || count != 2 {
+		t.Errorf("Remind() with a failing hook = %d, %v, want 2 items and an error", count, err)
+	}
+}
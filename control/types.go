@@ -7,9 +7,25 @@ import (
 
 const DefaultPageSize = 10
 
+// DefaultSearchLimit is the maximum number of hits returned by Search
+const DefaultSearchLimit = 50
+
+// DefaultSuggestionLimit is the maximum number of candidates returned by Suggest
+const DefaultSuggestionLimit = 10
+
 type Controller struct {
 	logger *log.Logger
 	data   *data.Database
+
+	undoStack []mutation // most recent last
+	redoStack []mutation // most recently undone last
+}
+
+// mutation is a change made through the controller during the session
+type mutation struct {
+	description string
+	undo        func() error
+	redo        func() error
 }
 
 type ColumnCondition struct {
@@ -21,9 +37,17 @@ type ColumnCondition struct {
 
 type ListRowsOptions struct {
 	Category string
-	Filters  data.RowData // Optional
-	Page     int          // Optional
-	PageSize int          // Optional
+	Filters  data.RowData    // Optional, column = value conditions
+	Where    []data.Filter   // Optional, conditions that must all match
+	AnyOf    [][]data.Filter // Optional, groups where at least one condition must match
+	Sort     []data.SortKey  // Optional, defaults to newest first
+	Page     int             // Optional
+	PageSize int             // Optional
+
+	// Cursor continues from a previous result's NextCursor instead of using Page.
+	// Rows are not counted in that case, as with SkipTotal.
+	Cursor    string // Optional
+	SkipTotal bool   // Optional, leave TotalRows and TotalPages at zero; later pages then need a Cursor
 }
 
 type ListRowsResult struct {
@@ -32,6 +56,7 @@ type ListRowsResult struct {
 	CurrentPage int
 	TotalPages  int
 	PageSize    int
+	NextCursor  string // set when rows were listed without counting and more rows follow
 }
 
 type OpenItemRequest struct {
@@ -40,11 +65,14 @@ type OpenItemRequest struct {
 }
 
 type OpenItemResponse struct {
-	Success bool
-	Error   error
+	Success    bool
+	Error      error
+	Validation []ValidationResult // one entry per rejected field
 }
 
 type ValidationResult struct {
+	Field   string
+	Rule    string // check that failed, empty when valid
 	IsValid bool
 	Message string
 }
@@ -0,0 +1,96 @@
+package control
+
+import (
+	log "Attimo/logging"
+	"errors"
+	"fmt"
+)
+
+// ErrNothingToUndo is returned by Undo when no mutation of the session is left to undo
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+// ErrNothingToRedo is returned by Redo when no undone mutation is left to redo
+var ErrNothingToRedo = errors.New("nothing to redo")
+
+// pushMutation records a new mutation. Anything undone before can no longer be redone.
+func (c *Controller) pushMutation(m mutation) {
+	c.undoStack = append(c.undoStack, m)
+	c.redoStack = nil
+}
+
+// revertibleMutation returns a mutation for the change just made to an item.
+// It is undone by reverting the change in the item history, and redone by
+// reverting that revert in turn.
+func (c *Controller) revertibleMutation(description, category string, itemID int) (mutation, error) {
+	target, err := c.data.LatestHistoryID(category, itemID)
+	if err != nil {
+		return mutation{}, err
+	}
+
+	revert := func() error {
+		if err := c.data.RevertRow(category, itemID, target); err != nil {
+			return err
+		}
+		// the revert is the change to revert next time
+		latest, err := c.data.LatestHistoryID(category, itemID)
+		if err != nil {
+			return err
+		}
+		target = latest
+		return nil
+	}
+	return mutation{description: description, undo: revert, redo: revert}, nil
+}
+
+// recordRevertible pushes the mutation of a change just made to an item.
+// The change itself succeeded, so a failure only costs the ability to undo it.
+func (c *Controller) recordRevertible(logger *log.Logger, description, category string, itemID int) {
+	m, err := c.revertibleMutation(description, category, itemID)
+	if err != nil {
+		logger.LogWarn("Cannot undo %s: %v", description, err)
+		return
+	}
+	c.pushMutation(m)
+}
+
+// Undo reverts the most recent mutation of the session and returns its description
+func (c *Controller) Undo(logger *log.Logger) (string, error) {
+	if logger == nil {
+		return "", fmt.Errorf(log.LoggerNilString)
+	}
+	if len(c.undoStack) == 0 {
+		return "", ErrNothingToUndo
+	}
+
+	m := c.undoStack[len(c.undoStack)-1]
+	if err := m.undo(); err != nil {
+		logger.LogErr("Failed to undo %s: %v", m.description, err)
+		return "", fmt.Errorf("failed to undo %s: %w", m.description, err)
+	}
+
+	c.undoStack = c.undoStack[:len(c.undoStack)-1]
+	c.redoStack = append(c.redoStack, m)
+	logger.LogInfo("Undid %s", m.description)
+	return m.description, nil
+}
+
+// Redo applies the most recently undone mutation again and returns its description
+func (c *Controller) Redo(logger *log.Logger) (string, error) {
+	if logger == nil {
+		return "", fmt.Errorf(log.LoggerNilString)
+	}
+	if len(c.redoStack) == 0 {
+		return "", ErrNothingToRedo
+	}
+
+	m := c.redoStack[len(c.redoStack)-1]
+	if err := m.redo(); err != nil {
+		logger.LogErr("Failed to redo %s: %v", m.description, err)
+		return "", fmt.Errorf("failed to redo %s: %w", m.description, err)
+	}
+
+	c.redoStack = c.redoStack[:len(c.redoStack)-1]
+	c.undoStack = append(c.undoStack, m)
+	logger.LogInfo("Redid %s", m.description)
+	return m.description, nil
+}
@@ -0,0 +1,212 @@
+package control
+
+import (
+	"Attimo/database"
+	log "Attimo/logging"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+// setupTestController creates a controller on a fresh database with a Habits category
+func setupTestController(t *testing.T) (*Controller, *log.Logger) {
+	t.Helper()
+
+	logger, err := log.InitLoggingWithWriter(io.Discard)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	db, err := database.SetupDatabase(filepath.Join(t.TempDir(), "attimo.db"), logger)
+	if err != nil {
+		t.Fatalf("Failed to set up database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	// Opened, Closed, Note, Deadline, Recurring of the default datatypes
+	template := database.CategoryTemplate{Name: "Habits", ColumnsID: []int{1, 2, 3, 9, 19}}
+	if err := db.CreateCategory(template); err != nil {
+		t.Fatalf("Failed to create test category: %v", err)
+	}
+
+	c, err := New(db, logger)
+	if err != nil {
+		t.Fatalf("Failed to create controller: %v", err)
+	}
+	return c, logger
+}
+
+// note returns the Note of an item, failing when the item is not readable
+func note(t *testing.T, c *Controller, id int) interface{} {
+	t.Helper()
+	row, err := c.data.ReadRow("Habits", id)
+	if err != nil {
+		t.Fatalf("ReadRow(%d) error = %v", id, err)
+	}
+	return row["Note"]
+}
+
+// exists reports whether an item is readable, that is neither missing nor in the trash
+func exists(c *Controller, id int) bool {
+	_, err := c.data.ReadRow("Habits", id)
+	return err == nil
+}
+
+// isClosed reports whether an item has a Closed value
+func isClosed(t *testing.T, c *Controller, id int) bool {
+	t.Helper()
+	row, err := c.data.ReadRow("Habits", id)
+	if err != nil {
+		t.Fatalf("ReadRow(%d) error = %v", id, err)
+	}
+	return row["Closed"] != nil && row["Closed"] != ""
+}
+
+// isPending reports whether an item is tracked as pending
+func isPending(t *testing.T, c *Controller, id int) bool {
+	t.Helper()
+	pointers, err := c.data.GetPendingPointers()
+	if err != nil {
+		t.Fatalf("GetPendingPointers() error = %v", err)
+	}
+	want := database.ItemRef{Category: "Habits", ID: id}.String()
+	for _, pointer := range pointers {
+		if pointer == want {
+			return true
+		}
+	}
+	return false
+}
+
+// step runs Undo or Redo, failing the test on error
+func step(t *testing.T, name string, run func(*log.Logger) (string, error), logger *log.Logger) {
+	t.Helper()
+	if _, err := run(logger); err != nil {
+		t.Fatalf("%s() error = %v", name, err)
+	}
+}
+
+func TestUndoCreate(t *testing.T) {
+	c, logger := setupTestController(t)
+
+	id, err := c.CreateRow(logger, "Habits", database.RowData{"Opened": "2024-05-01 09:00", "Note": "Stretch"})
+	if err != nil {
+		t.Fatalf("CreateRow() error = %v", err)
+	}
+
+	step(t, "Undo", c.Undo, logger)
+	if exists(c, id) || isPending(t, c, id) {
+		t.Errorf("undone item %d is still active", id)
+	}
+
+	step(t, "Redo", c.Redo, logger)
+	if !exists(c, id) || !isPending(t, c, id) {
+		t.Errorf("redone item %d is not active and pending", id)
+	}
+}
+
+func TestUndoUpdate(t *testing.T) {
+	c, logger := setupTestController(t)
+
+	id, err := c.CreateRow(logger, "Habits", database.RowData{"Opened": "2024-05-01 09:00", "Note": "before"})
+	if err != nil {
+		t.Fatalf("CreateRow() error = %v", err)
+	}
+	if err := c.UpdateRow(logger, "Habits", id, database.RowData{"Note": "after"}); err != nil {
+		t.Fatalf("UpdateRow() error = %v", err)
+	}
+
+	// Every revert must target the change made by the previous one
+	sequence := []struct {
+		name string
+		run  func(*log.Logger) (string, error)
+		want string
+	}{
+		{name: "Undo", run: c.Undo, want: "before"},
+		{name: "Redo", run: c.Redo, want: "after"},
+		{name: "Undo", run: c.Undo, want: "before"},
+		{name: "Redo", run: c.Redo, want: "after"},
+	}
+	for i, s := range sequence {
+		step(t, s.name, s.run, logger)
+		if got := note(t, c, id); got != s.want {
+			t.Fatalf("step %d %s: Note = %v, want %s", i, s.name, got, s.want)
+		}
+	}
+}
+
+func TestUndoClose(t *testing.T) {
+	c, logger := setupTestController(t)
+
+	id, err := c.CreateRow(logger, "Habits", database.RowData{"Opened": "2024-05-01 09:00", "Note": "Once"})
+	if err != nil {
+		t.Fatalf("CreateRow() error = %v", err)
+	}
+	if _, err := c.CloseItem(logger, "Habits", id, "2024-05-02 10:00:00"); err != nil {
+		t.Fatalf("CloseItem() error = %v", err)
+	}
+
+	step(t, "Undo", c.Undo, logger)
+	if isClosed(t, c, id) || !isPending(t, c, id) {
+		t.Errorf("undone close left item %d closed or untracked", id)
+	}
+
+	step(t, "Redo", c.Redo, logger)
+	if !isClosed(t, c, id) || isPending(t, c, id) {
+		t.Errorf("redone close left item %d open or pending", id)
+	}
+}
+
+func TestUndoRecurringClose(t *testing.T) {
+	c, logger := setupTestController(t)
+
+	id, err := c.CreateRow(logger, "Habits", database.RowData{"Opened": "2024-05-01 09:00", "Recurring": "Daily", "Note": "Stretch"})
+	if err != nil {
+		t.Fatalf("CreateRow() error = %v", err)
+	}
+	nextID, err := c.CloseItem(logger, "Habits", id, "2024-05-01 10:00:00")
+	if err != nil {
+		t.Fatalf("CloseItem() error = %v", err)
+	}
+	if nextID == 0 {
+		t.Fatalf("CloseItem() did not create the next occurrence")
+	}
+
+	step(t, "Undo", c.Undo, logger)
+	if isClosed(t, c, id) || !isPending(t, c, id) {
+		t.Errorf("undone close left item %d closed or untracked", id)
+	}
+	if exists(c, nextID) || isPending(t, c, nextID) {
+		t.Errorf("undone close left the next occurrence %d active", nextID)
+	}
+
+	step(t, "Redo", c.Redo, logger)
+	if !isClosed(t, c, id) || isPending(t, c, id) {
+		t.Errorf("redone close left item %d open or pending", id)
+	}
+	if !exists(c, nextID) || !isPending(t, c, nextID) {
+		t.Errorf("redone close did not restore the next occurrence %d", nextID)
+	}
+}
+
+func TestNewMutationClearsRedo(t *testing.T) {
+	c, logger := setupTestController(t)
+
+	if _, err := c.CreateRow(logger, "Habits", database.RowData{"Opened": "2024-05-01 09:00", "Note": "first"}); err != nil {
+		t.Fatalf("CreateRow() error = %v", err)
+	}
+	step(t, "Undo", c.Undo, logger)
+
+	if _, err := c.CreateRow(logger, "Habits", database.RowData{"Opened": "2024-05-01 09:00", "Note": "second"}); err != nil {
+		t.Fatalf("CreateRow() error = %v", err)
+	}
+	if _, err := c.Redo(logger); !errors.Is(err, ErrNothingToRedo) {
+		t.Errorf("Redo() after a new mutation error = %v, want %v", err, ErrNothingToRedo)
+	}
+
+	// Only the new mutation is left to undo
+	step(t, "Undo", c.Undo, logger)
+	if _, err := c.Undo(logger); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("second Undo() error = %v, want %v", err, ErrNothingToUndo)
+	}
+}
@@ -0,0 +1,219 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// createCategoriesTable creates the registry of category tables
+func createCategoriesTable(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+        CREATE TABLE IF NOT EXISTS categories (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+            updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+            deleted_at DATETIME DEFAULT NULL,
+            name TEXT NOT NULL UNIQUE,
+            display_name TEXT NOT NULL,
+            description TEXT NOT NULL DEFAULT '',
+            icon TEXT NOT NULL DEFAULT '',
+            colour TEXT NOT NULL DEFAULT ''
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create categories table: %w", err)
+	}
+	return nil
+}
+
+// migrateCategoriesRegistry creates the categories table and registers every existing category table
+func migrateCategoriesRegistry(tx *sql.Tx) error {
+	if err := createCategoriesTable(tx); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`
+		SELECT name
+		FROM sqlite_master
+		WHERE type = 'table'
+		  AND name NOT LIKE 'sqlite_%'
+		ORDER BY name
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to query table names: %w", err)
+	}
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan table name: %w", err)
+		}
+		if isReservedTableName(name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	// Default categories keep their descriptions, anything else is registered by name
+	defaults := make(map[string]CategoryTemplate)
+	for _, template := range getDefaultCategories() {
+		defaults[template.Name] = template
+	}
+
+	for _, name := range names {
+		template, ok := defaults[name]
+		if !ok {
+			template = CategoryTemplate{Name: name}
+		}
+		if err := registerCategory(tx, template); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerCategory adds a category table to the registry
+func registerCategory(tx *sql.Tx, template CategoryTemplate) error {
+	displayName := strings.TrimSpace(template.DisplayName)
+	if displayName == "" {
+		displayName = template.Name
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO categories (name, display_name, description, icon, colour)
+		VALUES (?, ?, ?, ?, ?)
+	`, template.Name, displayName, template.Description, template.Icon, template.Colour)
+	if err != nil {
+		return fmt.Errorf("failed to register category %s: %w", template.Name, err)
+	}
+	return nil
+}
+
+// checkCategoryActive returns an error unless the category is registered and not archived
+func checkCategoryActive(tx *sql.Tx, categoryName string) error {
+	var deletedAt sql.NullTime
+	err := tx.QueryRow(`
+		SELECT deleted_at
+		FROM categories
+		WHERE name = ?
+	`, categoryName).Scan(&deletedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("category %s does not exist", categoryName)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up category %s: %w", categoryName, err)
+	}
+	if deletedAt.Valid {
+		return fmt.Errorf("category %s is archived", categoryName)
+	}
+	return nil
+}
+
+// ListCategories returns the registered categories ordered by display name.
+// Archived categories are only included when includeArchived is true.
+func (db *Database) ListCategories(includeArchived bool) ([]Category, error) {
+	query := `
+		SELECT id, created_at, updated_at, deleted_at, name, display_name, description, icon, colour
+		FROM categories
+	`
+	if !includeArchived {
+		query += " WHERE deleted_at IS NULL"
+	}
+	query += " ORDER BY display_name COLLATE NOCASE, name"
+
+	rows, err := db.DB.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query categories: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []Category
+	for rows.Next() {
+		var c Category
+		err := rows.Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt, &c.DeletedAt, &c.Name, &c.DisplayName, &c.Description, &c.Icon, &c.Colour)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		categories = append(categories, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return categories, nil
+}
+
+// UpdateCategoryDetails changes the display name, description, icon and colour of a category
+func (db *Database) UpdateCategoryDetails(category Category) error {
+	displayName := strings.TrimSpace(category.DisplayName)
+	if displayName == "" {
+		displayName = category.Name
+	}
+
+	result, err := db.DB.Exec(`
+		UPDATE categories
+		SET display_name = ?,
+		    description = ?,
+		    icon = ?,
+		    colour = ?,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE name = ?
+	`, displayName, category.Description, category.Icon, category.Colour, category.Name)
+	if err != nil {
+		return fmt.Errorf("failed to update category %s: %w", category.Name, err)
+	}
+
+	return expectAffected(result, fmt.Errorf("category %s does not exist", category.Name))
+}
+
+// ArchiveCategory hides a category without dropping its table
+func (db *Database) ArchiveCategory(categoryName string) error {
+	result, err := db.DB.Exec(`
+		UPDATE categories
+		SET deleted_at = CURRENT_TIMESTAMP,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE name = ?
+		  AND deleted_at IS NULL
+	`, categoryName)
+	if err != nil {
+		return fmt.Errorf("failed to archive category %s: %w", categoryName, err)
+	}
+
+	return expectAffected(result, fmt.Errorf("no active category %s", categoryName))
+}
+
+// RestoreCategory makes an archived category visible again
+func (db *Database) RestoreCategory(categoryName string) error {
+	result, err := db.DB.Exec(`
+		UPDATE categories
+		SET deleted_at = NULL,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE name = ?
+		  AND deleted_at IS NOT NULL
+	`, categoryName)
+	if err != nil {
+		return fmt.Errorf("failed to restore category %s: %w", categoryName, err)
+	}
+
+	return expectAffected(result, fmt.Errorf("no archived category %s", categoryName))
+}
+
+// expectAffected returns notFound when the statement did not change any row
+func expectAffected(result sql.Result, notFound error) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf(affectedRowsErrorString, err)
+	}
+	if rowsAffected == 0 {
+		return notFound
+	}
+	return nil
+}
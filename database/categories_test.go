@@ -0,0 +1,77 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestCategoriesRegistry(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	// The migration registers pre-existing category tables
+	categories, err := db.GetCategories()
+	if err != nil {
+		t.Fatalf("GetCategories() error = %v", err)
+	}
+	if len(categories) != 1 || categories[0] != "General" {
+		t.Fatalf("GetCategories() = %v, want [General]", categories)
+	}
+
+	template := CategoryTemplate{Name: "Books", ColumnsID: []int{1}, DisplayName: "Reading list", Description: "Books to read", Icon: "📚"}
+	if err := db.CreateCategory(template); err != nil {
+		t.Fatalf("CreateCategory() error = %v", err)
+	}
+
+	list, err := db.ListCategories(false)
+	if err != nil {
+		t.Fatalf("ListCategories() error = %v", err)
+	}
+	if len(list) != 2 || list[1].Name != "Books" || list[1].DisplayName != "Reading list" || list[1].Icon != "📚" {
+		t.Fatalf("ListCategories() = %+v, want General and Books with details", list)
+	}
+	if list[0].DisplayName != "General" {
+		t.Errorf("ListCategories() display name = %s, want the table name by default", list[0].DisplayName)
+	}
+
+	if err := db.ArchiveCategory("Books"); err != nil {
+		t.Fatalf("ArchiveCategory() error = %v", err)
+	}
+	if err := db.ArchiveCategory("Books"); err == nil {
+		t.Errorf("ArchiveCategory() archived a category twice")
+	}
+
+	categories, err = db.GetCategories()
+	if err != nil {
+		t.Fatalf("GetCategories() error = %v", err)
+	}
+	if len(categories) != 1 {
+		t.Errorf("GetCategories() = %v, archived category still listed", categories)
+	}
+
+	list, err = db.ListCategories(true)
+	if err != nil {
+		t.Fatalf("ListCategories() error = %v", err)
+	}
+	if len(list) != 2 || !list[1].DeletedAt.Valid {
+		t.Errorf("ListCategories(true) = %+v, want the archived category", list)
+	}
+
+	if _, err := db.CreateRow("Books", RowData{"Note": "Dune"}); err == nil {
+		t.Errorf("CreateRow() accepted a row in an archived category")
+	}
+
+	if err := db.RestoreCategory("Books"); err != nil {
+		t.Fatalf("RestoreCategory() error = %v", err)
+	}
+	if _, err := db.CreateRow("Books", RowData{"Note": "Dune"}); err != nil {
+		t.Errorf("CreateRow() after restore error = %v", err)
+	}
+
+	details := Category{Name: "Books", DisplayName: "Library", Colour: "#205c63"}
+	if err := db.UpdateCategoryDetails(details); err != nil {
+		t.Errorf("UpdateCategoryDetails() error = %v", err)
+	}
+	if err := db.UpdateCategoryDetails(Category{Name: "Movies"}); err == nil {
+		t.Errorf("UpdateCategoryDetails() updated a missing category")
+	}
+}
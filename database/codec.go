@@ -0,0 +1,353 @@
+package database
+
+import (
+	"Attimo/timeparse"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// getColumnTypes maps every column of a category to its declared VariableType.
+// The CRUD columns are included, so whole rows can be decoded.
+func getColumnTypes(q queryer, categoryName string) (map[string]string, error) {
+	rows, err := q.Query(`
+		SELECT p.name, d.variable_type
+		FROM pragma_table_info(?) AS p
+		JOIN datatypes AS d ON d.name = p.name
+	`, categoryName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query column types: %w", err)
+	}
+	defer rows.Close()
+
+	types := map[string]string{
+		"id":         IntType,
+		"created_at": TimeType,
+		"updated_at": TimeType,
+		"deleted_at": TimeType,
+	}
+	for rows.Next() {
+		var name, variableType string
+		if err := rows.Scan(&name, &variableType); err != nil {
+			return nil, fmt.Errorf("failed to scan column type: %w", err)
+		}
+		types[name] = variableType
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return types, nil
+}
+
+// scanRow reads the current row of rows into a RowData with raw driver values
+func scanRow(rows *sql.Rows, columns []string) (RowData, error) {
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+
+	if err := rows.Scan(valuePtrs...); err != nil {
+		return nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	row := make(RowData)
+	for i, col := range columns {
+		row[col] = values[i]
+	}
+	return row, nil
+}
+
+// decodeRow converts the raw driver values of a row to their declared Go types.
+// Values that cannot be decoded are kept as they are and reported to the logger.
+func (db *Database) decodeRow(row RowData, types map[string]string) {
+	for col, raw := range row {
+		variableType, ok := types[col]
+		if !ok {
+			continue
+		}
+
+		// CRUD timestamps are written by SQLite in UTC, keep them as the driver returns them
+		if crudColumns[col] && variableType == TimeType {
+			continue
+		}
+
+		value, err := decodeValue(variableType, raw)
+		if err != nil {
+			if db.logger != nil {
+				db.logger.LogWarn("Could not decode column %s: %v", col, err)
+			}
+			continue
+		}
+		row[col] = value
+	}
+}
+
+// encodeRow converts the Go values of a row to their stored representation
+func encodeRow(data RowData, types map[string]string) (RowData, error) {
+	encoded := make(RowData, len(data))
+	for col, value := range data {
+		stored, err := encodeValue(types[col], value)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", col, err)
+		}
+		encoded[col] = stored
+	}
+	return encoded, nil
+}
+
+// decodeValue converts a raw driver value to the Go type declared by variableType:
+// int, float64, bool, string, time.Time or []string. NULL decodes to nil.
+func decodeValue(variableType string, raw interface{}) (interface{}, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	if b, ok := raw.([]byte); ok {
+		raw = string(b)
+	}
+
+	switch variableType {
+	case IntType:
+		switch v := raw.(type) {
+		case int64:
+			return int(v), nil
+		case float64:
+			return int(v), nil
+		case string:
+			return strconv.Atoi(strings.TrimSpace(v))
+		}
+	case FloatType:
+		switch v := raw.(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		case string:
+			return strconv.ParseFloat(strings.TrimSpace(v), 64)
+		}
+	case BoolType:
+		switch v := raw.(type) {
+		case bool:
+			return v, nil
+		case int64:
+			return v != 0, nil
+		case string:
+			return strconv.ParseBool(strings.TrimSpace(v))
+		}
+	case TimeType:
+		switch v := raw.(type) {
+		case time.Time:
+			// The driver reads stored wall clock times as UTC
+			return time.Date(v.Year(), v.Month(), v.Day(), v.Hour(), v.Minute(), v.Second(), v.Nanosecond(), time.Local), nil
+		case string:
+			return time.ParseInLocation(datetimeFormat, strings.TrimSpace(v), time.Local)
+		}
+	case csvType:
+		if v, ok := raw.(string); ok {
+			return splitCSV(v), nil
+		}
+	case StringType:
+		switch v := raw.(type) {
+		case string:
+			return v, nil
+		case time.Time:
+			return v.Format(datetimeFormat), nil
+		default:
+			return fmt.Sprint(v), nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type: %s", variableType)
+	}
+
+	return nil, fmt.Errorf("cannot decode %T as %s", raw, variableType)
+}
+
+// encodeValue converts a Go value to the representation stored for variableType.
+// Strings are passed through unchanged, so raw user input can still be stored.
+func encodeValue(variableType string, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if str, ok := value.(string); ok {
+		return str, nil
+	}
+
+	switch variableType {
+	case IntType:
+		switch v := value.(type) {
+		case int:
+			return int64(v), nil
+		case int8:
+			return int64(v), nil
+		case int16:
+			return int64(v), nil
+		case int32:
+			return int64(v), nil
+		case int64:
+			return v, nil
+		case uint8:
+			return int64(v), nil
+		case uint16:
+			return int64(v), nil
+		case uint32:
+			return int64(v), nil
+		}
+	case FloatType:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case float32:
+			return float64(v), nil
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		}
+	case BoolType:
+		if v, ok := value.(bool); ok {
+			if v {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		}
+	case TimeType:
+		if v, ok := value.(time.Time); ok {
+			return v.Format(datetimeFormat), nil
+		}
+	case csvType:
+		if v, ok := value.([]string); ok {
+			return joinCSV(v), nil
+		}
+	case StringType:
+		return fmt.Sprint(value), nil
+	default:
+		return nil, fmt.Errorf("unsupported type: %s", variableType)
+	}
+
+	return nil, fmt.Errorf("cannot store %T as %s", value, variableType)
+}
+
+// splitCSV splits a stored comma separated value into its trimmed, non-empty items
+func splitCSV(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// joinCSV stores a list as a comma separated value
+func joinCSV(items []string) string {
+	trimmed := make([]string, 0, len(items))
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			trimmed = append(trimmed, item)
+		}
+	}
+	return strings.Join(trimmed, ",")
+}
+
+// CoerceValue parses a textual value into the Go type declared by variableType,
+// so that it can be validated and stored with its real type.
+// Values that are not strings, and empty strings, are returned unchanged.
+func CoerceValue(variableType string, value interface{}) (interface{}, error) {
+	str, ok := value.(string)
+	if !ok {
+		// Whole numbers are valid floats
+		if variableType == FloatType {
+			switch v := value.(type) {
+			case int:
+				return float64(v), nil
+			case int64:
+				return float64(v), nil
+			}
+		}
+		return value, nil
+	}
+
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return "", nil
+	}
+
+	switch variableType {
+	case StringType:
+		return value, nil
+	case IntType:
+		i, err := strconv.Atoi(str)
+		if err != nil {
+			return nil, fmt.Errorf("expected a whole number, got %q", str)
+		}
+		return i, nil
+	case FloatType:
+		f, err := strconv.ParseFloat(strings.Replace(str, ",", ".", 1), 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected a number, got %q", str)
+		}
+		return f, nil
+	case BoolType:
+		switch strings.ToLower(str) {
+		case "yes", "y", "on":
+			return true, nil
+		case "no", "n", "off":
+			return false, nil
+		}
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			return nil, fmt.Errorf("expected yes or no, got %q", str)
+		}
+		return b, nil
+	case TimeType:
+		t, err := timeparse.Parse(str, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("expected a date like %s or tomorrow 9am, got %q", datetimeFormat, str)
+		}
+		return t, nil
+	case csvType:
+		return splitCSV(str), nil
+	default:
+		return nil, fmt.Errorf("unsupported type: %s", variableType)
+	}
+}
+
+// storedValue converts a raw driver value back to the representation SQLite stores,
+// so that it can be compared with or written to the column again
+func storedValue(raw interface{}) interface{} {
+	switch v := raw.(type) {
+	case time.Time:
+		return v.Format(datetimeFormat)
+	case []byte:
+		return string(v)
+	default:
+		return v
+	}
+}
+
+// fromJSONNumber converts a number decoded with json.Decoder.UseNumber to int64 or float64
+func fromJSONNumber(value interface{}) interface{} {
+	number, ok := value.(json.Number)
+	if !ok {
+		return value
+	}
+	if n, err := number.Int64(); err == nil {
+		return n
+	}
+	if f, err := number.Float64(); err == nil {
+		return f
+	}
+	return value
+}
@@ -0,0 +1,205 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecodeValue(t *testing.T) {
+	due := time.Date(2024, 5, 1, 18, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		name         string
+		variableType string
+		raw          interface{}
+		want         interface{}
+		wantErr      bool
+	}{
+		{name: "null", variableType: IntType, raw: nil, want: nil},
+		{name: "int", variableType: IntType, raw: int64(42), want: 42},
+		{name: "int from text", variableType: IntType, raw: "42", want: 42},
+		{name: "float", variableType: FloatType, raw: 3.5, want: 3.5},
+		{name: "float from int", variableType: FloatType, raw: int64(3), want: 3.0},
+		{name: "bool", variableType: BoolType, raw: int64(1), want: true},
+		{name: "string from bytes", variableType: StringType, raw: []byte("hi"), want: "hi"},
+		{name: "time from driver", variableType: TimeType, raw: time.Date(2024, 5, 1, 18, 30, 0, 0, time.UTC), want: due},
+		{name: "time from text", variableType: TimeType, raw: "2024-05-01 18:30:00", want: due},
+		{name: "csv", variableType: csvType, raw: "a, b,,c", want: []string{"a", "b", "c"}},
+		{name: "empty csv", variableType: csvType, raw: "", want: []string{}},
+		{name: "bad int", variableType: IntType, raw: "many", wantErr: true},
+		{name: "bad time", variableType: TimeType, raw: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeValue(tt.variableType, tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeValue() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeValue(t *testing.T) {
+	tests := []struct {
+		name         string
+		variableType string
+		value        interface{}
+		want         interface{}
+		wantErr      bool
+	}{
+		{name: "null", variableType: IntType, value: nil, want: nil},
+		{name: "raw string", variableType: IntType, value: "42", want: "42"},
+		{name: "int", variableType: IntType, value: 42, want: int64(42)},
+		{name: "float", variableType: FloatType, value: 2.5, want: 2.5},
+		{name: "bool", variableType: BoolType, value: false, want: int64(0)},
+		{name: "time", variableType: TimeType, value: time.Date(2024, 5, 1, 18, 30, 0, 0, time.Local), want: "2024-05-01 18:30:00"},
+		{name: "csv", variableType: csvType, value: []string{"a", " b ", ""}, want: "a,b"},
+		{name: "mismatch", variableType: IntType, value: time.Now(), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodeValue(tt.variableType, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("encodeValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("encodeValue() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTypedRowRoundTrip(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	datatypes := []Datatype{
+		{Name: "Due", VariableType: TimeType, CompletionValue: NoCompletion, CompletionSort: NoSort, ValueCheck: nonemptyCheck, FillBehavior: Open},
+		{Name: "Tags", VariableType: csvType, CompletionValue: NoCompletion, CompletionSort: NoSort, ValueCheck: nonemptyCheck, FillBehavior: Open},
+		{Name: "Done", VariableType: BoolType, CompletionValue: NoCompletion, CompletionSort: NoSort, ValueCheck: NoCheck, FillBehavior: Open},
+		{Name: "Distance_km", VariableType: FloatType, CompletionValue: NoCompletion, CompletionSort: NoSort, ValueCheck: nonemptyCheck, FillBehavior: Open},
+		{Name: "Laps", VariableType: IntType, CompletionValue: NoCompletion, CompletionSort: NoSort, ValueCheck: nonemptyCheck, FillBehavior: Open},
+	}
+	createTestCategory(t, db, "Workouts", datatypes, 1) // Note
+
+	want := RowData{
+		"Note":        "Morning run",
+		"Due":         time.Date(2024, 5, 1, 7, 0, 0, 0, time.Local),
+		"Tags":        []string{"run", "outdoor"},
+		"Done":        true,
+		"Distance_km": 10.5,
+		"Laps":        4,
+	}
+	if _, err := db.CreateRow("Workouts", want); err != nil {
+		t.Fatalf("CreateRow() error = %v", err)
+	}
+
+	got, err := db.ReadRow("Workouts", 1)
+	if err != nil {
+		t.Fatalf("ReadRow() error = %v", err)
+	}
+	for key, value := range want {
+		if !reflect.DeepEqual(got[key], value) {
+			t.Errorf("ReadRow() got[%s] = %#v, want %#v", key, got[key], value)
+		}
+	}
+	if got["id"] != 1 {
+		t.Errorf("ReadRow() id = %#v, want 1", got["id"])
+	}
+
+	if err := db.UpdateRow("Workouts", 1, RowData{"Done": false, "Tags": []string{"run"}}); err != nil {
+		t.Fatalf("UpdateRow() error = %v", err)
+	}
+	rows, _, err := db.ListRows("Workouts", RowData{"Done": false}, 1, 10)
+	if err != nil {
+		t.Fatalf("ListRows() error = %v", err)
+	}
+	if len(rows) != 1 || !reflect.DeepEqual(rows[0]["Tags"], []string{"run"}) || rows[0]["Done"] != false {
+		t.Errorf("ListRows() = %v, want the updated typed row", rows)
+	}
+}
+
+func TestCoerceValue(t *testing.T) {
+	tests := []struct {
+		name         string
+		variableType string
+		value        interface{}
+		want         interface{}
+		wantErr      bool
+	}{
+		{name: "int", variableType: IntType, value: " 7 ", want: 7},
+		{name: "bad int", variableType: IntType, value: "7.5", wantErr: true},
+		{name: "float", variableType: FloatType, value: "7.5", want: 7.5},
+		{name: "float with comma", variableType: FloatType, value: "7,5", want: 7.5},
+		{name: "int as float", variableType: FloatType, value: 7, want: 7.0},
+		{name: "bool", variableType: BoolType, value: "yes", want: true},
+		{name: "bad bool", variableType: BoolType, value: "maybe", wantErr: true},
+		{name: "datetime", variableType: TimeType, value: "2024-05-01 18:30", want: time.Date(2024, 5, 1, 18, 30, 0, 0, time.Local)},
+		{name: "date", variableType: TimeType, value: "01-05-2024", want: time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)},
+		{name: "date and clock", variableType: TimeType, value: "2024-05-01 6:30pm", want: time.Date(2024, 5, 1, 18, 30, 0, 0, time.Local)},
+		{name: "iso with offset", variableType: TimeType, value: "2024-05-01T18:30:00Z", want: time.Date(2024, 5, 1, 18, 30, 0, 0, time.UTC).In(time.Local)},
+		{name: "bad time", variableType: TimeType, value: "soon", wantErr: true},
+		{name: "csv", variableType: csvType, value: "a,b", want: []string{"a", "b"}},
+		{name: "string", variableType: StringType, value: "as is ", want: "as is "},
+		{name: "empty", variableType: IntType, value: "", want: ""},
+		{name: "typed", variableType: IntType, value: 3, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CoerceValue(tt.variableType, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CoerceValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CoerceValue() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateRowCoercesText(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	datatypes := []Datatype{
+		{Name: "Rating", VariableType: IntType, CompletionValue: NoCompletion, CompletionSort: NoSort, ValueCheck: RangeCheck + "(1,10)", FillBehavior: Open},
+		{Name: "Distance_km", VariableType: FloatType, CompletionValue: NoCompletion, CompletionSort: NoSort, ValueCheck: RangeCheck + "(0.5,42.2)", FillBehavior: Open},
+	}
+	createTestCategory(t, db, "Runs", datatypes, 1) // Note
+
+	tests := []struct {
+		name    string
+		data    RowData
+		wantErr bool
+	}{
+		{name: "text in range", data: RowData{"Note": "Park", "Rating": "8", "Distance_km": "10.5"}, wantErr: false},
+		{name: "int out of range", data: RowData{"Note": "Park", "Rating": "11", "Distance_km": "10.5"}, wantErr: true},
+		{name: "float out of range", data: RowData{"Note": "Park", "Rating": "8", "Distance_km": "0.2"}, wantErr: true},
+		{name: "not a number", data: RowData{"Note": "Park", "Rating": "eight", "Distance_km": "10.5"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := db.CreateRow("Runs", tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CreateRow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Numbers are stored with their type, not as text
+	var storedType string
+	if err := db.DB.QueryRow(`SELECT typeof(Rating) FROM Runs WHERE id = 1`).Scan(&storedType); err != nil {
+		t.Fatalf("Failed to read stored type: %v", err)
+	}
+	if storedType != "integer" {
+		t.Errorf("Rating stored as %s, want integer", storedType)
+	}
+}
@@ -0,0 +1,223 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// crudColumns are the bookkeeping columns every category table carries
+var crudColumns = map[string]bool{
+	"id":         true,
+	"created_at": true,
+	"updated_at": true,
+	"deleted_at": true,
+}
+
+// tableColumn holds the name and declared SQLite type of a category column
+type tableColumn struct {
+	Name string
+	Type string
+}
+
+// getTableColumns returns the non-CRUD columns of a category table in declaration order
+func getTableColumns(tx *sql.Tx, categoryName string) ([]tableColumn, error) {
+	rows, err := tx.Query(`
+		SELECT name, type
+		FROM pragma_table_info(?)
+		WHERE name != 'id'
+		  AND name != 'created_at'
+		  AND name != 'updated_at'
+		  AND name != 'deleted_at'
+		ORDER BY cid
+	`, categoryName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query column info: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []tableColumn
+	for rows.Next() {
+		var col tableColumn
+		if err := rows.Scan(&col.Name, &col.Type); err != nil {
+			return nil, fmt.Errorf("failed to scan column info: %w", err)
+		}
+		columns = append(columns, col)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return columns, nil
+}
+
+// findColumn returns the index of a column by name, or -1 if it is missing
+func findColumn(columns []tableColumn, name string) int {
+	for i, col := range columns {
+		if strings.EqualFold(col.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkCategoryExists returns an error when the category table is missing or reserved
+func checkCategoryExists(tx *sql.Tx, categoryName string) error {
+	if err := validateCategoryName(categoryName); err != nil {
+		return err
+	}
+	exists, err := tableExists(tx, categoryName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("category %s does not exist", categoryName)
+	}
+	return nil
+}
+
+// AddColumn adds a datatype-backed column to an existing category
+func (db *Database) AddColumn(categoryName, datatypeName string) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf(failedToBeginTxString, err)
+	}
+	defer tx.Rollback()
+
+	if err := checkCategoryExists(tx, categoryName); err != nil {
+		return err
+	}
+
+	datatype, err := GetDatatypeByName(tx, datatypeName)
+	if err != nil {
+		return err
+	}
+
+	columns, err := getTableColumns(tx, categoryName)
+	if err != nil {
+		return err
+	}
+	if findColumn(columns, datatype.Name) != -1 {
+		return fmt.Errorf("column %s already exists in category %s", datatype.Name, categoryName)
+	}
+
+	sqliteType, err := toSQLiteType(datatype.VariableType)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(
+		"ALTER TABLE %s ADD COLUMN %s %s",
+		quoteIdentifier(categoryName),
+		quoteIdentifier(datatype.Name),
+		sqliteType,
+	)
+	if _, err := tx.Exec(query); err != nil {
+		return fmt.Errorf("failed to add column %s to %s: %w", datatype.Name, categoryName, err)
+	}
+
+	return tx.Commit()
+}
+
+// RemoveColumn removes a column from a category by rebuilding its table.
+// Rows keep their id and CRUD timestamps, only the values of the removed column are lost.
+func (db *Database) RemoveColumn(categoryName, columnName string) error {
+	if crudColumns[strings.ToLower(columnName)] {
+		return fmt.Errorf("column %s cannot be removed", columnName)
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf(failedToBeginTxString, err)
+	}
+	defer tx.Rollback()
+
+	if err := checkCategoryExists(tx, categoryName); err != nil {
+		return err
+	}
+
+	columns, err := getTableColumns(tx, categoryName)
+	if err != nil {
+		return err
+	}
+
+	index := findColumn(columns, columnName)
+	if index == -1 {
+		return fmt.Errorf("column %s does not exist in category %s", columnName, categoryName)
+	}
+	if len(columns) == 1 {
+		return fmt.Errorf("cannot remove the last column of category %s", categoryName)
+	}
+
+	// Pending items can only be closed while the Closed column exists
+	removed := columns[index].Name
+	if removed == "Closed" && findColumn(columns, "Opened") != -1 {
+		return fmt.Errorf("column Closed is required while category %s has Opened", categoryName)
+	}
+
+	remaining := append(columns[:index:index], columns[index+1:]...)
+
+	columnDefs := make([]string, 0, len(remaining))
+	columnNames := []string{"id", "created_at", "updated_at", "deleted_at"}
+	for _, col := range remaining {
+		columnDefs = append(columnDefs, fmt.Sprintf("%s %s", quoteIdentifier(col.Name), col.Type))
+		columnNames = append(columnNames, col.Name)
+	}
+
+	tmpName := categoryName + "_rebuild"
+	exists, err := tableExists(tx, tmpName)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("cannot rebuild %s: table %s already exists", categoryName, tmpName)
+	}
+	if err := createCategoryTable(tx, tmpName, columnDefs); err != nil {
+		return err
+	}
+
+	quotedColumns := strings.Join(quoteIdentifiers(columnNames), ", ")
+	copyQuery := fmt.Sprintf(
+		"INSERT INTO %s (%s) SELECT %s FROM %s",
+		quoteIdentifier(tmpName),
+		quotedColumns,
+		quotedColumns,
+		quoteIdentifier(categoryName),
+	)
+	if _, err := tx.Exec(copyQuery); err != nil {
+		return fmt.Errorf("failed to copy rows of %s: %w", categoryName, err)
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf("DROP TABLE %s", quoteIdentifier(categoryName))); err != nil {
+		return fmt.Errorf("failed to drop table %s: %w", categoryName, err)
+	}
+
+	renameQuery := fmt.Sprintf(
+		"ALTER TABLE %s RENAME TO %s",
+		quoteIdentifier(tmpName),
+		quoteIdentifier(categoryName),
+	)
+	if _, err := tx.Exec(renameQuery); err != nil {
+		return fmt.Errorf("failed to rename table %s: %w", tmpName, err)
+	}
+
+	_, err = tx.Exec("DELETE FROM datatype_overrides WHERE category = ? AND datatype = ?", categoryName, removed)
+	if err != nil {
+		return fmt.Errorf("failed to remove datatype override of %s: %w", removed, err)
+	}
+
+	// Without Opened the category no longer tracks pending items
+	if removed == "Opened" {
+		if err := db.removeCategoryFromPending(tx, categoryName); err != nil {
+			return err
+		}
+	}
+
+	// The removed column no longer contributes to the search index
+	if err := reindexCategory(tx, categoryName); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
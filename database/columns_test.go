@@ -0,0 +1,93 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestAddColumn(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	if err := db.CreateCategory(CategoryTemplate{Name: "Books", ColumnsID: []int{1}}); err != nil {
+		t.Fatalf("Failed to create test category: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		category string
+		datatype string
+		wantErr  bool
+	}{
+		{name: "valid column", category: "Books", datatype: "Project", wantErr: false},
+		{name: "duplicate column", category: "Books", datatype: "Project", wantErr: true},
+		{name: "unknown datatype", category: "Books", datatype: "Rating", wantErr: true},
+		{name: "unknown category", category: "Movies", datatype: "Project", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := db.AddColumn(tt.category, tt.datatype)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AddColumn() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	columns, err := db.GetCategoryColumns("Books")
+	if err != nil {
+		t.Fatalf("GetCategoryColumns() error = %v", err)
+	}
+	if len(columns) != 2 || columns[1] != "Project" {
+		t.Errorf("GetCategoryColumns() = %v, want [Note Project]", columns)
+	}
+}
+
+func TestRemoveColumn(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	if _, err := db.CreateRow("General", RowData{"Note": "Keep me", "Project": "Drop me", "Location": "Here"}); err != nil {
+		t.Fatalf("Failed to create test row: %v", err)
+	}
+	if err := db.DeleteRow("General", 1); err != nil {
+		t.Fatalf("Failed to delete test row: %v", err)
+	}
+	if _, err := db.CreateRow("General", RowData{"Note": "Second", "Project": "Drop me", "Location": "There"}); err != nil {
+		t.Fatalf("Failed to create test row: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		category string
+		column   string
+		wantErr  bool
+	}{
+		{name: "valid column", category: "General", column: "Project", wantErr: false},
+		{name: "missing column", category: "General", column: "Project", wantErr: true},
+		{name: "crud column", category: "General", column: "deleted_at", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := db.RemoveColumn(tt.category, tt.column)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RemoveColumn() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Rows, ids and soft deletes survive the rebuild
+	row, err := db.ReadRow("General", 2)
+	if err != nil {
+		t.Fatalf("ReadRow() error = %v", err)
+	}
+	if row["Note"] != "Second" {
+		t.Errorf("ReadRow() Note = %v, want Second", row["Note"])
+	}
+	if _, ok := row["Project"]; ok {
+		t.Errorf("ReadRow() still returns removed column")
+	}
+	if _, err := db.ReadRow("General", 1); err == nil {
+		t.Errorf("ReadRow() returned a soft deleted row after rebuild")
+	}
+}
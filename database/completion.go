@@ -0,0 +1,289 @@
+package database
+
+import (
+	"Attimo/timeparse"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// valueStats tracks how often and where a prior value was used
+type valueStats struct {
+	value string
+	count int
+	first int // position of the first use, oldest first
+	last  int // position of the latest use
+}
+
+// hasPrefixFold reports whether s starts with prefix, ignoring case
+func hasPrefixFold(s, prefix string) bool {
+	return strings.HasPrefix(strings.ToLower(s), strings.ToLower(prefix))
+}
+
+// Suggest returns completion candidates for a column that start with prefix, following the
+// CompletionValue and CompletionSort of the column datatype. A limit <= 0 means no limit.
+// For list columns the last item of prefix is completed and the earlier items are kept.
+func (db *Database) Suggest(categoryName, column, prefix string, limit int) ([]string, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf(failedToBeginTxString, err)
+	}
+	defer tx.Rollback()
+
+	if err := checkCategoryExists(tx, categoryName); err != nil {
+		return nil, err
+	}
+	columns, err := getTableColumns(tx, categoryName)
+	if err != nil {
+		return nil, err
+	}
+	if findColumn(columns, column) == -1 {
+		return nil, fmt.Errorf("column %s does not exist in category %s", column, categoryName)
+	}
+
+	dt, err := GetCategoryDatatype(tx, categoryName, column)
+	if err != nil {
+		return nil, err
+	}
+	completion, err := parseRule(dt.CompletionValue)
+	if err != nil {
+		return nil, fmt.Errorf("invalid completion %q of %s: %w", dt.CompletionValue, column, err)
+	}
+
+	// List values complete their last item
+	head, current := "", prefix
+	if dt.VariableType == csvType {
+		if i := strings.LastIndex(prefix, ","); i != -1 {
+			head, current = prefix[:i+1]+" ", strings.TrimSpace(prefix[i+1:])
+		}
+	}
+
+	var candidates []string
+	switch completion.name {
+	case NoCompletion:
+		return nil, nil
+	case LastCompletion:
+		candidates, err = priorValues(tx, categoryName, column, dt.VariableType, LastSort)
+		if len(candidates) > 1 {
+			candidates = candidates[:1]
+		}
+	case UniqueCompletion:
+		candidates, err = priorValues(tx, categoryName, column, dt.VariableType, dt.CompletionSort)
+	case SetCompletion:
+		candidates, err = setValues(tx, categoryName, column, dt.VariableType, completion.argStrings(), dt.CompletionSort)
+	case DateCompletion:
+		candidates = dateSuggestions(time.Now())
+	case FileCompletion:
+		candidates, err = fileSuggestions(current)
+	default:
+		return nil, fmt.Errorf("unknown completion %q", dt.CompletionValue)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool)
+	for _, item := range splitCSV(head) {
+		used[strings.ToLower(item)] = true
+	}
+
+	var suggestions []string
+	for _, candidate := range candidates {
+		if !hasPrefixFold(candidate, current) || used[strings.ToLower(candidate)] {
+			continue
+		}
+		suggestions = append(suggestions, head+candidate)
+		if limit > 0 && len(suggestions) == limit {
+			break
+		}
+	}
+	return suggestions, nil
+}
+
+// columnUsage collects the distinct values stored in a column, splitting list values into items
+func columnUsage(tx *sql.Tx, categoryName, column, variableType string) (map[string]*valueStats, error) {
+	table, err := quoteCategory(categoryName)
+	if err != nil {
+		return nil, err
+	}
+	col := quoteIdentifier(column)
+
+	query := fmt.Sprintf(`
+        SELECT %s FROM %s
+        WHERE deleted_at IS NULL AND %s IS NOT NULL AND %s != ''
+        ORDER BY id
+    `, col, table, col, col)
+	rows, err := tx.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query values of %s: %w", column, err)
+	}
+	defer rows.Close()
+
+	stats := make(map[string]*valueStats)
+	position := 0
+	for rows.Next() {
+		var raw interface{}
+		if err := rows.Scan(&raw); err != nil {
+			return nil, fmt.Errorf("failed to scan value: %w", err)
+		}
+
+		text := fmt.Sprint(storedValue(raw))
+		items := []string{text}
+		if variableType == csvType {
+			items = splitCSV(text)
+		}
+		for _, item := range items {
+			position++
+			s, ok := stats[item]
+			if !ok {
+				s = &valueStats{value: item, first: position}
+				stats[item] = s
+			}
+			s.count++
+			s.last = position
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return stats, nil
+}
+
+// sortStats orders values by the CompletionSort rule
+func sortStats(stats []*valueStats, order string) {
+	sort.SliceStable(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		switch order {
+		case FrequencySort:
+			if a.count != b.count {
+				return a.count > b.count
+			}
+			return a.last > b.last
+		case LastSort:
+			return a.last > b.last
+		case alphabeticSort:
+			return strings.ToLower(a.value) < strings.ToLower(b.value)
+		default:
+			return a.first < b.first
+		}
+	})
+}
+
+// priorValues returns the distinct values used before in a column, in the given order
+func priorValues(tx *sql.Tx, categoryName, column, variableType, order string) ([]string, error) {
+	usage, err := columnUsage(tx, categoryName, column, variableType)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]*valueStats, 0, len(usage))
+	for _, s := range usage {
+		stats = append(stats, s)
+	}
+	// Map iteration is random, start from the order of first use
+	sort.Slice(stats, func(i, j int) bool { return stats[i].first < stats[j].first })
+	sortStats(stats, order)
+
+	values := make([]string, len(stats))
+	for i, s := range stats {
+		values[i] = s.value
+	}
+	return values, nil
+}
+
+// setValues returns the values of an in(...) completion. Without a sort they keep their declared order,
+// otherwise they are ranked by how they were used, with unused values last.
+func setValues(tx *sql.Tx, categoryName, column, variableType string, set []string, order string) ([]string, error) {
+	if order == NoSort || order == "" {
+		return set, nil
+	}
+
+	usage, err := columnUsage(tx, categoryName, column, variableType)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]*valueStats, len(set))
+	for i, value := range set {
+		if s, ok := usage[value]; ok {
+			stats[i] = &valueStats{value: value, count: s.count, first: s.first, last: s.last}
+		} else {
+			stats[i] = &valueStats{value: value, first: len(usage) + i + 1}
+		}
+	}
+	sortStats(stats, order)
+
+	values := make([]string, len(stats))
+	for i, s := range stats {
+		values[i] = s.value
+	}
+	return values, nil
+}
+
+// dateSuggestions offers the current time and a few common moments after it
+func dateSuggestions(now time.Time) []string {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	daysToMonday := (int(time.Monday) - int(today.Weekday()) + 7) % 7
+	if daysToMonday == 0 {
+		daysToMonday = 7
+	}
+
+	moments := []time.Time{
+		now,
+		now.Add(time.Hour),
+		today.Add(18 * time.Hour),
+		today.AddDate(0, 0, 1).Add(9 * time.Hour),
+		today.AddDate(0, 0, daysToMonday).Add(9 * time.Hour),
+	}
+
+	var suggestions []string
+	seen := make(map[string]bool)
+	for _, moment := range moments {
+		text := moment.Format(timeparse.ShortLayout)
+		if !seen[text] {
+			seen[text] = true
+			suggestions = append(suggestions, text)
+		}
+	}
+	return suggestions
+}
+
+// fileSuggestions lists the paths of the directory named by prefix. Directories end with a separator.
+// Hidden entries are only listed when the name being completed starts with a dot.
+func fileSuggestions(prefix string) ([]string, error) {
+	dir, base := filepath.Split(prefix)
+	listed := dir
+	if strings.HasPrefix(listed, "~") {
+		home, err := os.UserHomeDir()
+		if err == nil {
+			listed = home + listed[1:]
+		}
+	}
+	if listed == "" {
+		listed = "."
+	}
+
+	entries, err := os.ReadDir(listed)
+	if err != nil {
+		// Directories that do not exist yet simply have nothing to offer
+		return nil, nil
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+		if entry.IsDir() {
+			name += string(filepath.Separator)
+		}
+		paths = append(paths, dir+name)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
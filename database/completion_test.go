@@ -0,0 +1,130 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// setupContactsCategory creates a Contacts category with one column per completion rule and fills it
+func setupContactsCategory(t *testing.T, db *TestDB) {
+	t.Helper()
+
+	datatypes := []Datatype{
+		{Name: "Person", VariableType: StringType, CompletionValue: UniqueCompletion, CompletionSort: FrequencySort, ValueCheck: NoCheck, FillBehavior: Open},
+		{Name: "City", VariableType: StringType, CompletionValue: UniqueCompletion, CompletionSort: LastSort, ValueCheck: NoCheck, FillBehavior: Open},
+		{Name: "Company", VariableType: StringType, CompletionValue: UniqueCompletion, CompletionSort: alphabeticSort, ValueCheck: NoCheck, FillBehavior: Open},
+		{Name: "Mood", VariableType: StringType, CompletionValue: LastCompletion, CompletionSort: NoSort, ValueCheck: NoCheck, FillBehavior: Open},
+		{Name: "Labels", VariableType: csvType, CompletionValue: UniqueCompletion, CompletionSort: FrequencySort, ValueCheck: NoCheck, FillBehavior: Open},
+		{Name: "Urgency", VariableType: StringType, CompletionValue: SetCompletion + "(Low,Medium,High)", CompletionSort: FrequencySort, ValueCheck: NoCheck, FillBehavior: Open},
+		{Name: "Channel", VariableType: StringType, CompletionValue: SetCompletion + "(Mail,Phone,Chat)", CompletionSort: NoSort, ValueCheck: NoCheck, FillBehavior: Open},
+	}
+	createTestCategory(t, db, "Contacts", datatypes, 1) // Note
+
+	rows := []RowData{
+		{"Person": "Bob", "City": "Berlin", "Company": "Zeta", "Mood": "calm", "Labels": "work,family", "Urgency": "High"},
+		{"Person": "Alice", "City": "Paris", "Company": "acme", "Mood": "busy", "Labels": "work", "Urgency": "High"},
+		{"Person": "Alice", "City": "Bonn", "Company": "Beta", "Labels": "friends,work", "Urgency": "Low"},
+		{"Person": "Anna", "City": "Paris", "Note": "deleted later", "Labels": "secret"},
+	}
+	for _, row := range rows {
+		if _, err := db.CreateRow("Contacts", row); err != nil {
+			t.Fatalf("Failed to create test row: %v", err)
+		}
+	}
+	if err := db.DeleteRow("Contacts", 4); err != nil {
+		t.Fatalf("Failed to delete test row: %v", err)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	setupContactsCategory(t, db)
+
+	tests := []struct {
+		name    string
+		column  string
+		prefix  string
+		limit   int
+		want    []string
+		wantErr bool
+	}{
+		{name: "frequency", column: "Person", want: []string{"Alice", "Bob"}},
+		{name: "prefix ignores case", column: "Person", prefix: "a", want: []string{"Alice"}},
+		{name: "no match", column: "Person", prefix: "Z", want: nil},
+		{name: "last", column: "City", want: []string{"Bonn", "Paris", "Berlin"}},
+		{name: "limit", column: "City", limit: 2, want: []string{"Bonn", "Paris"}},
+		{name: "alphabetic", column: "Company", want: []string{"acme", "Beta", "Zeta"}},
+		{name: "last value", column: "Mood", want: []string{"busy"}},
+		{name: "list items", column: "Labels", want: []string{"work", "friends", "family"}},
+		{name: "list completes last item", column: "Labels", prefix: "work, f", want: []string{"work, friends", "work, family"}},
+		{name: "set by frequency", column: "Urgency", want: []string{"High", "Low", "Medium"}},
+		{name: "set in declared order", column: "Channel", want: []string{"Mail", "Phone", "Chat"}},
+		{name: "no completion", column: "Note", want: nil},
+		{name: "unknown column", column: "Missing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := db.Suggest("Contacts", tt.column, tt.prefix, tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Suggest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Suggest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := db.Suggest("Missing", "Person", "", 0); err == nil {
+		t.Errorf("Suggest() of a missing category succeeded")
+	}
+}
+
+func TestDateSuggestions(t *testing.T) {
+	// 2024-05-02 is a Thursday
+	now := time.Date(2024, 5, 2, 10, 30, 0, 0, time.Local)
+	want := []string{"2024-05-02 10:30", "2024-05-02 11:30", "2024-05-02 18:00", "2024-05-03 09:00", "2024-05-06 09:00"}
+	if got := dateSuggestions(now); !reflect.DeepEqual(got, want) {
+		t.Errorf("dateSuggestions() = %q, want %q", got, want)
+	}
+
+	// On a Monday the next Monday is a week away
+	monday := time.Date(2024, 5, 6, 8, 0, 0, 0, time.Local)
+	if got := dateSuggestions(monday); got[len(got)-1] != "2024-05-13 09:00" {
+		t.Errorf("dateSuggestions() = %q, want next Monday 2024-05-13 09:00", got)
+	}
+}
+
+func TestFileSuggestions(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"notes.txt", "news.md", ".hidden"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "nested"), 0o755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	prefix := dir + string(filepath.Separator)
+	got, err := fileSuggestions(prefix + "n")
+	if err != nil {
+		t.Fatalf("fileSuggestions() error = %v", err)
+	}
+	want := []string{prefix + "nested" + string(filepath.Separator), prefix + "news.md", prefix + "notes.txt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fileSuggestions() = %q, want %q", got, want)
+	}
+
+	got, _ = fileSuggestions(prefix + ".")
+	if len(got) != 4 {
+		t.Errorf("fileSuggestions() with a dot = %q, want the hidden file too", got)
+	}
+	if got, _ := fileSuggestions(filepath.Join(dir, "missing") + string(filepath.Separator)); got != nil {
+		t.Errorf("fileSuggestions() of a missing directory = %q, want none", got)
+	}
+}
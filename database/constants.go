@@ -2,12 +2,13 @@
 package database
 
 import (
+	"Attimo/timeparse"
 	"strings"
 )
 
 // Date format
 const (
-	dateFormat         = "02-01-2006"
+	datetimeFormat     = timeparse.Layout // storage format of TimeType columns
 	dbSetupErrorString = "Failed to set up database: %v"
 )
 
@@ -50,6 +51,14 @@ const (
 	PhoneCheck    = "phone"
 	FileCheck     = "file_exists"
 	DateCheck     = "date"
+
+	RegexCheck      = "regex"      // regex(pattern), the whole value must match
+	MinLengthCheck  = "min_length" // min_length(n), in characters, or items for lists
+	MaxLengthCheck  = "max_length" // max_length(n)
+	AndCheck        = "and"        // and(rule, ...), every rule must pass
+	OrCheck         = "or"         // or(rule, ...), at least one rule must pass
+	NotCheck        = "not"        // not(rule)
+	RecurrenceCheck = "recurrence" // Daily, Weekly, Monthly, Yearly or a rule like "every 2 weeks on Mon,Thu"
 )
 
 // Fill behavior
@@ -58,10 +67,16 @@ const (
 	Close = "close"
 )
 
-// Current version
-const currentVersion = "1.0.0"
+// Schema versions
+const (
+	// baseVersion is the schema written by createDefaultDB, before any migration
+	baseVersion = "1.0.0"
+	// currentVersion is the schema expected by this binary, reached by applying migrations
+	currentVersion = "1.7.0"
+)
 
-// ComposeArguments takes a list of strings and returns a string with the arguments formatted as a function call
+// ComposeArguments takes a list of strings and returns a string with the arguments formatted as a function call.
+// Arguments containing commas, parentheses or quotes are quoted, so the result can be parsed back.
 func ComposeArguments(args ...string) string {
 	if len(args) == 0 {
 		return ""
@@ -72,7 +87,7 @@ func ComposeArguments(args ...string) string {
 	builder.WriteString("(")
 
 	for i := 1; i < len(args); i++ {
-		builder.WriteString(args[i])
+		builder.WriteString(quoteRuleArg(args[i]))
 		if i != len(args)-1 {
 			builder.WriteString(",")
 		}
@@ -103,7 +118,7 @@ func getDefaultDatatypes() []Datatype {
 		{Name: "Tags", VariableType: csvType, CompletionValue: UniqueCompletion, CompletionSort: FrequencySort, ValueCheck: nonemptyCheck, FillBehavior: Open},
 		{Name: "Progress", VariableType: IntType, CompletionValue: SetCompletion + "(0,25,50,75,100)", CompletionSort: LastSort, ValueCheck: nonemptyCheck, FillBehavior: Close},
 		{Name: "Budget", VariableType: IntType, CompletionValue: NoCompletion, CompletionSort: NoSort, ValueCheck: nonemptyCheck, FillBehavior: Open},
-		{Name: "Recurring", VariableType: StringType, CompletionValue: SetCompletion + "(Daily,Weekly,Monthly,Yearly)", CompletionSort: LastSort, ValueCheck: nonemptyCheck, FillBehavior: Open},
+		{Name: "Recurring", VariableType: StringType, CompletionValue: SetCompletion + "(Daily,Weekly,Monthly,Yearly)", CompletionSort: LastSort, ValueCheck: RecurrenceCheck, FillBehavior: Open},
 		{Name: "Dependencies", VariableType: csvType, CompletionValue: NoCompletion, CompletionSort: NoSort, ValueCheck: nonemptyCheck, FillBehavior: Open},
 		// Dependencies could also be int[]
 	}
@@ -112,8 +127,8 @@ func getDefaultDatatypes() []Datatype {
 // getDefaultCategories returns the default category configurations
 func getDefaultCategories() []CategoryTemplate {
 	return []CategoryTemplate{
-		{Name: "General", ColumnsID: []int{1, 2, 3, 4, 6, 13}},   // Opened, Closed, Note, Project, Location, File
-		{Name: "Contact", ColumnsID: []int{1, 2, 3, 11, 12, 13}}, // Opened, Closed, Note, Email, Phone, File
-		{Name: "Financial", ColumnsID: []int{1, 2, 3, 6, 8}},     // Opened, Closed, Note, Location, Cost_EUR
+		{Name: "General", ColumnsID: []int{1, 2, 3, 4, 6, 13}, Description: "Tasks, notes and activities"},    // Opened, Closed, Note, Project, Location, File
+		{Name: "Contact", ColumnsID: []int{1, 2, 3, 11, 12, 13}, Description: "People and how to reach them"}, // Opened, Closed, Note, Email, Phone, File
+		{Name: "Financial", ColumnsID: []int{1, 2, 3, 6, 8}, Description: "Expenses and purchases"},           // Opened, Closed, Note, Location, Cost_EUR
 	}
 }
@@ -1,9 +1,11 @@
 package database
 
 import (
+	"Attimo/timeparse"
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 )
 
 const (
@@ -12,17 +14,51 @@ const (
 	failedToBeginTxString   = "failed to begin transaction: %w"
 )
 
-// CreateRow inserts a new row into a category table
-func (db *Database) CreateRow(categoryName string, data RowData) error {
+// CreateRow inserts a new row into a category table and returns its id
+func (db *Database) CreateRow(categoryName string, data RowData) (int, error) {
 	tx, err := db.DB.Begin()
 	if err != nil {
-		return fmt.Errorf(failedToBeginTxString, err)
+		return 0, fmt.Errorf(failedToBeginTxString, err)
 	}
 	defer tx.Rollback()
 
+	// Only active categories accept new rows
+	if err := checkCategoryActive(tx, categoryName); err != nil {
+		return 0, err
+	}
+
+	itemID, err := db.insertRow(tx, categoryName, data)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return itemID, nil
+}
+
+// insertRow validates and inserts a row, keeping the search index, history and pending tracking in line
+func (db *Database) insertRow(tx *sql.Tx, categoryName string, data RowData) (int, error) {
+	table, err := quoteCategory(categoryName)
+	if err != nil {
+		return 0, err
+	}
+
 	// Validate input data
-	if err := db.validateInputData(tx, categoryName, data, false); err != nil {
-		return fmt.Errorf("data validation failed: %w", err)
+	data, err = db.validateInputData(tx, categoryName, data, false)
+	if err != nil {
+		return 0, fmt.Errorf("data validation failed: %w", err)
+	}
+
+	// Convert typed values to their stored representation
+	types, err := getColumnTypes(tx, categoryName)
+	if err != nil {
+		return 0, err
+	}
+	data, err = encodeRow(data, types)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode row: %w", err)
 	}
 
 	// Prepare column and value placeholders
@@ -31,7 +67,7 @@ func (db *Database) CreateRow(categoryName string, data RowData) error {
 	values := make([]interface{}, 0, len(data))
 
 	for col, val := range data {
-		columns = append(columns, col)
+		columns = append(columns, quoteIdentifier(col))
 		placeholders = append(placeholders, "?")
 		values = append(values, val)
 	}
@@ -39,46 +75,80 @@ func (db *Database) CreateRow(categoryName string, data RowData) error {
 	// Construct and execute the INSERT query
 	query := fmt.Sprintf(
 		"INSERT INTO %s (%s) VALUES (%s)",
-		categoryName,
+		table,
 		strings.Join(columns, ", "),
 		strings.Join(placeholders, ", "),
 	)
 
 	result, err := tx.Exec(query, values...)
 	if err != nil {
-		return fmt.Errorf("failed to insert row: %w", err)
+		return 0, fmt.Errorf("failed to insert row: %w", err)
 	}
 
 	itemID, err := result.LastInsertId()
 	if err != nil {
-		return fmt.Errorf("failed to get last insert id: %w", err)
+		return 0, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	if err := indexRow(tx, categoryName, int(itemID)); err != nil {
+		return 0, err
+	}
+
+	if err := recordChange(tx, HistoryCreate, categoryName, int(itemID), nil); err != nil {
+		return 0, err
 	}
 
 	// Add to pending if category has Opened field
-	columns, err = db.GetCategoryColumns(categoryName)
+	tableColumns, err := getTableColumns(tx, categoryName)
 	if err != nil {
-		return fmt.Errorf(columnsFetchErrorString, err)
+		return 0, fmt.Errorf(columnsFetchErrorString, err)
 	}
 
-	for _, col := range columns {
-		if col == "Opened" {
-			if err := db.addToPending(tx, categoryName, int(itemID)); err != nil {
-				return fmt.Errorf("failed to add to pending: %w", err)
-			}
-			break
+	if findColumn(tableColumns, "Opened") != -1 {
+		if err := db.addToPending(tx, categoryName, int(itemID)); err != nil {
+			return 0, fmt.Errorf("failed to add to pending: %w", err)
 		}
 	}
 
-	return tx.Commit()
+	return int(itemID), nil
 }
 
-func (db *Database) CloseItem(category string, itemID int, closeDate string) error {
+// CloseItem sets the Closed value of a pending item.
+// It fails with ErrBlocked while the item depends on items that are still pending.
+// When the item recurs, its next occurrence is created and its id returned, otherwise 0.
+func (db *Database) CloseItem(category string, itemID int, closeDate string) (int, error) {
+	table, err := quoteCategory(category)
+	if err != nil {
+		return 0, err
+	}
+
+	// The close time may be typed as "yesterday 18:30" or "-15", it is stored in the canonical layout
+	closed, err := timeparse.Parse(closeDate, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("invalid close time: %w", err)
+	}
+	closeDate = timeparse.Format(closed)
+
 	tx, err := db.DB.Begin()
 	if err != nil {
-		return fmt.Errorf(failedToBeginTxString, err)
+		return 0, fmt.Errorf(failedToBeginTxString, err)
 	}
 	defer tx.Rollback()
 
+	old, err := rowSnapshot(tx, category, itemID)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("no item found with id %d in category %s", itemID, category)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if closed := old["Closed"]; closed != nil && closed != "" {
+		return 0, fmt.Errorf("item %d in category %s is already closed", itemID, category)
+	}
+	if err := checkNotBlocked(tx, ItemRef{Category: category, ID: itemID}); err != nil {
+		return 0, err
+	}
+
 	// Update the main record
 	updateQuery := fmt.Sprintf(`
         UPDATE %s 
@@ -86,34 +156,51 @@ func (db *Database) CloseItem(category string, itemID int, closeDate string) err
             updated_at = CURRENT_TIMESTAMP
         WHERE id = ? 
         AND deleted_at IS NULL
-    `, category)
+    `, table)
 
 	result, err := tx.Exec(updateQuery, closeDate, itemID)
 	if err != nil {
-		return fmt.Errorf("failed to update item: %w", err)
+		return 0, fmt.Errorf("failed to update item: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf(affectedRowsErrorString, err)
+		return 0, fmt.Errorf(affectedRowsErrorString, err)
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("no item found with id %d in category %s", itemID, category)
+		return 0, fmt.Errorf("no item found with id %d in category %s", itemID, category)
 	}
 
 	// Remove from pending tracking
 	if err := db.removeFromPending(tx, category, itemID); err != nil {
-		return err
+		return 0, err
 	}
 
-	return tx.Commit()
+	if err := recordChange(tx, HistoryClose, category, itemID, old); err != nil {
+		return 0, err
+	}
+
+	nextID, err := db.spawnRecurrence(tx, category, old, closeDate)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nextID, nil
 }
 
 // ReadRow retrieves a single row from a category table
 func (db *Database) ReadRow(categoryName string, id int) (RowData, error) {
+	table, err := quoteCategory(categoryName)
+	if err != nil {
+		return nil, err
+	}
+
 	// Query for column names
-	columnQuery := fmt.Sprintf("SELECT * FROM %s WHERE id = ? AND deleted_at IS NULL LIMIT 1", categoryName)
+	columnQuery := fmt.Sprintf("SELECT * FROM %s WHERE id = ? AND deleted_at IS NULL LIMIT 1", table)
 	rows, err := db.DB.Query(columnQuery, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query row: %w", err)
@@ -129,27 +216,28 @@ func (db *Database) ReadRow(categoryName string, id int) (RowData, error) {
 		return nil, sql.ErrNoRows
 	}
 
-	// Create a slice of interface{} to hold the values of each column
-	values := make([]interface{}, len(columns))
-	valuePtrs := make([]interface{}, len(columns))
-	for i := range values {
-		valuePtrs[i] = &values[i]
-	}
-
-	if err := rows.Scan(valuePtrs...); err != nil {
-		return nil, fmt.Errorf("failed to scan row: %w", err)
+	result, err := scanRow(rows, columns)
+	if err != nil {
+		return nil, err
 	}
+	rows.Close()
 
-	// Create the result map
-	result := make(RowData)
-	for i, col := range columns {
-		result[col] = values[i]
+	// Convert raw values to their declared types
+	types, err := getColumnTypes(db.DB, categoryName)
+	if err != nil {
+		return nil, err
 	}
+	db.decodeRow(result, types)
 
 	return result, nil
 }
 
 func (db *Database) UpdateRow(categoryName string, id int, data RowData) error {
+	table, err := quoteCategory(categoryName)
+	if err != nil {
+		return err
+	}
+
 	tx, err := db.DB.Begin()
 	if err != nil {
 		return fmt.Errorf(failedToBeginTxString, err)
@@ -157,16 +245,32 @@ func (db *Database) UpdateRow(categoryName string, id int, data RowData) error {
 	defer tx.Rollback()
 
 	// Validate input data
-	if err := db.validateInputData(tx, categoryName, data, true); err != nil {
+	data, err = db.validateInputData(tx, categoryName, data, true)
+	if err != nil {
 		return fmt.Errorf("data validation failed: %w", err)
 	}
 
+	old, err := rowSnapshot(tx, categoryName, id)
+	if err != nil {
+		return err
+	}
+
+	// Convert typed values to their stored representation
+	types, err := getColumnTypes(tx, categoryName)
+	if err != nil {
+		return err
+	}
+	data, err = encodeRow(data, types)
+	if err != nil {
+		return fmt.Errorf("failed to encode row: %w", err)
+	}
+
 	// Prepare SET clause and values
 	setClauses := make([]string, 0, len(data))
 	values := make([]interface{}, 0, len(data)+1) // +1 for the ID
 
 	for col, val := range data {
-		setClauses = append(setClauses, fmt.Sprintf("%s = ?", col))
+		setClauses = append(setClauses, fmt.Sprintf("%s = ?", quoteIdentifier(col)))
 		values = append(values, val)
 	}
 
@@ -176,7 +280,7 @@ func (db *Database) UpdateRow(categoryName string, id int, data RowData) error {
 	// Construct the UPDATE query
 	query := fmt.Sprintf(
 		"UPDATE %s SET %s WHERE id = ? AND deleted_at IS NULL",
-		categoryName,
+		table,
 		strings.Join(setClauses, ", "),
 	)
 
@@ -194,6 +298,19 @@ func (db *Database) UpdateRow(categoryName string, id int, data RowData) error {
 		return sql.ErrNoRows
 	}
 
+	// Setting or clearing Closed changes whether the row is pending
+	if err := db.syncPending(tx, categoryName, id); err != nil {
+		return err
+	}
+
+	if err := indexRow(tx, categoryName, id); err != nil {
+		return err
+	}
+
+	if err := recordChange(tx, HistoryUpdate, categoryName, id, old); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -201,14 +318,31 @@ func (db *Database) UpdateRow(categoryName string, id int, data RowData) error {
 	return nil
 }
 
-// DeleteRow soft deletes a row by setting its deleted_at timestamp
+// DeleteRow soft deletes a row by setting its deleted_at timestamp.
+// The row stops being pending, RestoreRow brings it back.
 func (db *Database) DeleteRow(categoryName string, id int) error {
+	table, err := quoteCategory(categoryName)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf(failedToBeginTxString, err)
+	}
+	defer tx.Rollback()
+
+	old, err := rowSnapshot(tx, categoryName, id)
+	if err != nil {
+		return err
+	}
+
 	query := fmt.Sprintf(
 		"UPDATE %s SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL",
-		categoryName,
+		table,
 	)
 
-	result, err := db.DB.Exec(query, id)
+	result, err := tx.Exec(query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete row: %w", err)
 	}
@@ -222,28 +356,56 @@ func (db *Database) DeleteRow(categoryName string, id int) error {
 		return sql.ErrNoRows
 	}
 
-	return nil
+	if err := unindexRow(tx, categoryName, id); err != nil {
+		return err
+	}
+
+	// Deleted items are no longer waiting to be closed
+	if err := db.removeFromPending(tx, categoryName, id); err != nil {
+		return err
+	}
+
+	if err := recordHistory(tx, HistoryDelete, categoryName, id, old, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-// ListRows retrieves multiple rows from a category table, with pagination
+// ListRows retrieves multiple rows from a category table, with pagination.
+// Rows match when every column equals its filter value. A nil value matches
+// columns that are not set, NULL or empty, as with OpIsNull.
 func (db *Database) ListRows(categoryName string, filters RowData, page, pageSize int) ([]RowData, int, error) {
-	// Build WHERE clause from filters
-	whereClause := "deleted_at IS NULL"
-	values := make([]interface{}, 0)
-
-	if len(filters) > 0 {
-		conditions := make([]string, 0, len(filters))
-		for col, val := range filters {
-			conditions = append(conditions, fmt.Sprintf("%s = ?", col))
-			values = append(values, val)
-		}
-		whereClause += " AND " + strings.Join(conditions, " AND ")
+	return db.ListRowsQuery(categoryName, Query{Where: EqualFilters(filters)}, page, pageSize)
+}
+
+// ListRowsQuery retrieves the rows of a category table matching a query, with pagination.
+// It also returns the number of matching rows across all pages.
+func (db *Database) ListRowsQuery(categoryName string, q Query, page, pageSize int) ([]RowData, int, error) {
+	table, err := quoteCategory(categoryName)
+	if err != nil {
+		return nil, 0, err
 	}
 
+	builder, err := db.newQueryBuilder(categoryName)
+	if err != nil {
+		return nil, 0, err
+	}
+	whereClause, err := builder.where(q)
+	if err != nil {
+		return nil, 0, err
+	}
+	keys, err := builder.sortKeys(q.Sort)
+	if err != nil {
+		return nil, 0, err
+	}
+	orderClause := orderBy(keys)
+	values := builder.args
+
 	// Get total matching row count
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", categoryName, whereClause)
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", table, whereClause)
 	var total int
-	err := db.DB.QueryRow(countQuery, values...).Scan(&total)
+	err = db.DB.QueryRow(countQuery, values...).Scan(&total)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get total count: %w", err)
 	}
@@ -253,9 +415,10 @@ func (db *Database) ListRows(categoryName string, filters RowData, page, pageSiz
 
 	// Build main query with pagination
 	query := fmt.Sprintf(
-		"SELECT * FROM %s WHERE %s ORDER BY id DESC LIMIT ? OFFSET ?",
-		categoryName,
+		"SELECT * FROM %s WHERE %s ORDER BY %s LIMIT ? OFFSET ?",
+		table,
 		whereClause,
+		orderClause,
 	)
 	values = append(values, pageSize, offset)
 
@@ -277,20 +440,9 @@ func (db *Database) ListRows(categoryName string, filters RowData, page, pageSiz
 
 	// Iterate through rows
 	for rows.Next() {
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-		for i := range values {
-			valuePtrs[i] = &values[i]
-		}
-
-		if err := rows.Scan(valuePtrs...); err != nil {
-			return nil, 0, fmt.Errorf("failed to scan row: %w", err)
-		}
-
-		// Create row map
-		rowData := make(RowData)
-		for i, col := range columns {
-			rowData[col] = values[i]
+		rowData, err := scanRow(rows, columns)
+		if err != nil {
+			return nil, 0, err
 		}
 		result = append(result, rowData)
 	}
@@ -298,6 +450,16 @@ func (db *Database) ListRows(categoryName string, filters RowData, page, pageSiz
 	if err = rows.Err(); err != nil {
 		return nil, 0, fmt.Errorf("error iterating rows: %w", err)
 	}
+	rows.Close()
+
+	// Convert raw values to their declared types
+	types, err := getColumnTypes(db.DB, categoryName)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, rowData := range result {
+		db.decodeRow(rowData, types)
+	}
 
 	return result, total, nil
 }
@@ -2,8 +2,12 @@ package database
 
 import (
 	"database/sql"
+	"io"
 	"os"
 	"testing"
+	"time"
+
+	"Attimo/logging"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -24,9 +28,15 @@ func setupTestDB(t *testing.T) *TestDB {
 		t.Fatalf("Failed to create temp file: %v", err)
 	}
 
+	logger, err := logging.InitLoggingWithWriter(io.Discard)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
 	// Open database connection
 	db := &Database{
-		Path: tmpfile.Name(),
+		Path:   tmpfile.Name(),
+		logger: logger,
 	}
 
 	// Open the database connection
@@ -46,16 +56,56 @@ func setupTestDB(t *testing.T) *TestDB {
 		t.Fatalf("Failed to setup test tables: %v", err)
 	}
 
+	// Bring the test schema up to the current version
+	if err := db.migrate(); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
 	return &TestDB{
 		Database: db,
 		path:     tmpfile.Name(),
 	}
 }
 
+// createTestCategory creates the datatypes and a category with the baseIDs columns followed by them
+func createTestCategory(t *testing.T, db *TestDB, name string, datatypes []Datatype, baseIDs ...int) {
+	t.Helper()
+
+	ids := append([]int{}, baseIDs...)
+	for _, dt := range datatypes {
+		id, err := db.CreateDatatype(dt)
+		if err != nil {
+			t.Fatalf("Failed to create datatype %s: %v", dt.Name, err)
+		}
+		ids = append(ids, id)
+	}
+	if err := db.CreateCategory(CategoryTemplate{Name: name, ColumnsID: ids}); err != nil {
+		t.Fatalf("Failed to create test category %s: %v", name, err)
+	}
+}
+
 // setupTestTables creates the necessary tables for testing
 func setupTestTables(db *sql.DB) error {
-	// Create datatypes table
+	// Create metadata table at the base version
 	_, err := db.Exec(`
+    CREATE TABLE IF NOT EXISTS metadata (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        version TEXT NOT NULL
+    )
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("INSERT INTO metadata (version) VALUES (?)", baseVersion)
+	if err != nil {
+		return err
+	}
+
+	// Create datatypes table
+	_, err = db.Exec(`
     CREATE TABLE IF NOT EXISTS datatypes (
         id INTEGER PRIMARY KEY AUTOINCREMENT,
         name TEXT NOT NULL,
@@ -104,7 +154,17 @@ func setupTestTables(db *sql.DB) error {
 		return err
 	}
 
-	return nil
+	// Create pending table
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := createPendingTable(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // tearDown closes the database and removes the temporary file
@@ -158,7 +218,7 @@ func TestCreateRow(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := db.CreateRow(tt.categoryName, tt.data)
+			_, err := db.CreateRow(tt.categoryName, tt.data)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CreateRow() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -177,7 +237,7 @@ func TestReadRow(t *testing.T) {
 		"Location": "Test location",
 	}
 
-	err := db.CreateRow("General", testData)
+	_, err := db.CreateRow("General", testData)
 	if err != nil {
 		t.Fatalf("Failed to create test row: %v", err)
 	}
@@ -235,7 +295,7 @@ func TestUpdateRow(t *testing.T) {
 		"Location": "Initial location",
 	}
 
-	err := db.CreateRow("General", initialData)
+	_, err := db.CreateRow("General", initialData)
 	if err != nil {
 		t.Fatalf("Failed to create initial test row: %v", err)
 	}
@@ -311,7 +371,7 @@ func TestDeleteRow(t *testing.T) {
 		"Location": "Test location",
 	}
 
-	err := db.CreateRow("General", testData)
+	_, err := db.CreateRow("General", testData)
 	if err != nil {
 		t.Fatalf("Failed to create test row: %v", err)
 	}
@@ -360,6 +420,18 @@ func TestDeleteRow(t *testing.T) {
 	}
 }
 
+func TestCreateRowReturnsID(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	for want := 1; want <= 2; want++ {
+		id, err := db.CreateRow("General", RowData{"Note": "Note", "Project": "A", "Location": "X"})
+		if err != nil || id != want {
+			t.Errorf("CreateRow() = %d, %v, want id %d", id, err, want)
+		}
+	}
+}
+
 func TestListRows(t *testing.T) {
 	db := setupTestDB(t)
 	defer db.tearDown(t)
@@ -372,7 +444,7 @@ func TestListRows(t *testing.T) {
 	}
 
 	for _, data := range testData {
-		if err := db.CreateRow("General", data); err != nil {
+		if _, err := db.CreateRow("General", data); err != nil {
 			t.Fatalf("Failed to create test row: %v", err)
 		}
 	}
@@ -435,3 +507,74 @@ func TestListRows(t *testing.T) {
 		})
 	}
 }
+
+func TestListRowsNilFilter(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	testData := []RowData{
+		{"Note": "Set", "Project": "Project A", "Location": "Location X"},
+		{"Note": "Empty", "Project": "Project A", "Location": ""},
+		{"Note": "Missing", "Project": "Project A"},
+	}
+	for _, data := range testData {
+		if _, err := db.CreateRow("General", data); err != nil {
+			t.Fatalf("Failed to create test row: %v", err)
+		}
+	}
+
+	// A nil filter value matches rows where the column is not set, whether NULL or empty
+	rows, total, err := db.ListRows("General", RowData{"Location": nil}, 1, 10)
+	if err != nil {
+		t.Fatalf("ListRows() error = %v", err)
+	}
+	notes := make(map[interface{}]bool)
+	for _, row := range rows {
+		notes[row["Note"]] = true
+	}
+	if total != 2 || len(rows) != 2 || !notes["Empty"] || !notes["Missing"] {
+		t.Errorf("ListRows() with a nil filter = %v (total %d), want the empty and missing rows", rows, total)
+	}
+
+	// An empty string only matches itself
+	if _, total, err := db.ListRows("General", RowData{"Location": ""}, 1, 10); err != nil || total != 1 {
+		t.Errorf("ListRows() with an empty filter total = %d, %v, want 1", total, err)
+	}
+}
+
+func TestCloseItemParsesTime(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	setupChoresCategory(t, db)
+
+	for _, note := range []string{"Dishes", "Laundry"} {
+		if _, err := db.CreateRow("Chores", RowData{"Opened": "2024-05-01 09:00", "Note": note}); err != nil {
+			t.Fatalf("CreateRow() error = %v", err)
+		}
+	}
+
+	if _, err := db.CloseItem("Chores", 1, "whenever"); err == nil {
+		t.Errorf("CloseItem() accepted an invalid close time")
+	}
+	if _, err := db.CloseItem("Chores", 1, "2024-05-02 6pm"); err != nil {
+		t.Fatalf("CloseItem() error = %v", err)
+	}
+	if _, err := db.CloseItem("Chores", 2, "yesterday 18:30"); err != nil {
+		t.Fatalf("CloseItem() error = %v", err)
+	}
+
+	yesterday := time.Now().AddDate(0, 0, -1)
+	want := map[int]time.Time{
+		1: time.Date(2024, 5, 2, 18, 0, 0, 0, time.Local),
+		2: time.Date(yesterday.Year(), yesterday.Month(), yesterday.Day(), 18, 30, 0, 0, time.Local),
+	}
+	for id, closed := range want {
+		row, err := db.ReadRow("Chores", id)
+		if err != nil {
+			t.Fatalf("ReadRow() error = %v", err)
+		}
+		if got, ok := row["Closed"].(time.Time); !ok || !got.Equal(closed) {
+			t.Errorf("Closed of item %d = %v, want %v", id, row["Closed"], closed)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// cursorState is the content of an opaque cursor
+type cursorState struct {
+	Sort   string        `json:"s"` // ORDER BY clause the cursor was made for
+	Values []interface{} `json:"v"` // stored values of the sort keys of the last row returned
+}
+
+// encodeCursor returns the cursor positioned after a row with the given sort key values
+func encodeCursor(keys []SortKey, values []interface{}) string {
+	state, err := json.Marshal(cursorState{Sort: orderBy(keys), Values: values})
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(state)
+}
+
+// decodeCursor returns the sort key values stored in a cursor.
+// The cursor must have been made for the same sort keys.
+func decodeCursor(cursor string, keys []SortKey) ([]interface{}, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var state cursorState
+	if err := decoder.Decode(&state); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if state.Sort != orderBy(keys) || len(state.Values) != len(keys) {
+		return nil, fmt.Errorf("cursor does not belong to this sort order")
+	}
+
+	for i, v := range state.Values {
+		state.Values[i] = fromJSONNumber(v)
+	}
+	return state.Values, nil
+}
+
+// after returns the condition selecting the rows that come after values in the sort order.
+// SQLite sorts NULL before any value, so NULLs come first in ascending and last in descending order.
+func (b *queryBuilder) after(keys []SortKey, values []interface{}) string {
+	alternatives := make([]string, 0, len(keys))
+	for i, key := range keys {
+		col := quoteIdentifier(key.Column)
+		value := values[i]
+
+		var next string
+		switch {
+		case value == nil && key.Desc:
+			// nothing sorts after NULL in descending order
+		case value == nil:
+			next = fmt.Sprintf("%s IS NOT NULL", col)
+		case key.Desc:
+			next = fmt.Sprintf("(%s < ? OR %s IS NULL)", col, col)
+		default:
+			next = fmt.Sprintf("%s > ?", col)
+		}
+
+		if next != "" {
+			// the previous keys are equal to the cursor, this one is past it
+			terms := make([]string, 0, i+1)
+			for j := 0; j < i; j++ {
+				if values[j] == nil {
+					terms = append(terms, fmt.Sprintf("%s IS NULL", quoteIdentifier(keys[j].Column)))
+				} else {
+					terms = append(terms, fmt.Sprintf("%s = ?", quoteIdentifier(keys[j].Column)))
+					b.args = append(b.args, values[j])
+				}
+			}
+			terms = append(terms, next)
+			if value != nil {
+				b.args = append(b.args, value)
+			}
+			alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+		}
+	}
+
+	if len(alternatives) == 0 {
+		return "0"
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")"
+}
+
+// RowIterator streams the rows of a category one at a time.
+// It must be closed once the caller is done with it.
+//
+//	it, err := db.IterateRows("General", Query{}, "")
+//	...
+//	defer it.Close()
+//	for it.Next() {
+//		row := it.Row()
+//	}
+//	if err := it.Err(); err != nil {
+type RowIterator struct {
+	db      *Database
+	rows    *sql.Rows
+	columns []string
+	types   map[string]string
+	keys    []SortKey
+	row     RowData
+	last    []interface{} // stored sort key values of row
+	err     error
+}
+
+// IterateRows returns an iterator over the rows of a category matching a query.
+// A non-empty cursor, as returned by RowIterator.Cursor or ListRowsAfter, skips the rows up to it.
+func (db *Database) IterateRows(categoryName string, q Query, cursor string) (*RowIterator, error) {
+	return db.iterateRows(categoryName, q, cursor, 0)
+}
+
+// iterateRows is IterateRows returning at most limit rows, or every row when limit is 0
+func (db *Database) iterateRows(categoryName string, q Query, cursor string, limit int) (*RowIterator, error) {
+	table, err := quoteCategory(categoryName)
+	if err != nil {
+		return nil, err
+	}
+
+	builder, err := db.newQueryBuilder(categoryName)
+	if err != nil {
+		return nil, err
+	}
+	whereClause, err := builder.where(q)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := builder.sortKeys(q.Sort)
+	if err != nil {
+		return nil, err
+	}
+	if cursor != "" {
+		values, err := decodeCursor(cursor, keys)
+		if err != nil {
+			return nil, err
+		}
+		whereClause += " AND " + builder.after(keys, values)
+	}
+
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s ORDER BY %s", table, whereClause, orderBy(keys))
+	if limit > 0 {
+		query += " LIMIT ?"
+		builder.args = append(builder.args, limit)
+	}
+
+	rows, err := db.DB.Query(query, builder.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rows: %w", err)
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return nil, fmt.Errorf(columnsFetchErrorString, err)
+	}
+
+	return &RowIterator{
+		db:      db,
+		rows:    rows,
+		columns: columns,
+		types:   builder.types,
+		keys:    keys,
+	}, nil
+}
+
+// Next advances to the next row, returning false when there are no more rows or an error occurred
+func (it *RowIterator) Next() bool {
+	it.row = nil
+	if it.err != nil {
+		return false
+	}
+	if !it.rows.Next() {
+		if err := it.rows.Err(); err != nil {
+			it.err = fmt.Errorf("error iterating rows: %w", err)
+		}
+		return false
+	}
+
+	row, err := scanRow(it.rows, it.columns)
+	if err != nil {
+		it.err = err
+		return false
+	}
+
+	it.last = make([]interface{}, len(it.keys))
+	for i, key := range it.keys {
+		it.last[i] = storedValue(row[key.Column])
+	}
+	it.db.decodeRow(row, it.types)
+	it.row = row
+	return true
+}
+
+// Row returns the current row, with values converted to their declared types
+func (it *RowIterator) Row() RowData {
+	return it.row
+}
+
+// Cursor returns an opaque cursor positioned after the current row,
+// or an empty string before the first call to Next
+func (it *RowIterator) Cursor() string {
+	if it.last == nil {
+		return ""
+	}
+	return encodeCursor(it.keys, it.last)
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *RowIterator) Err() error {
+	return it.err
+}
+
+// Close releases the underlying query
+func (it *RowIterator) Close() error {
+	return it.rows.Close()
+}
+
+// ListRowsAfter returns a page of rows following the cursor, without counting the matching rows.
+// An empty cursor starts from the first row. The returned cursor fetches the next page,
+// and is empty once the last page has been returned.
+func (db *Database) ListRowsAfter(categoryName string, q Query, cursor string, pageSize int) ([]RowData, string, error) {
+	if pageSize < 1 {
+		return nil, "", fmt.Errorf("invalid page size %d", pageSize)
+	}
+
+	// One extra row tells whether there is a next page
+	it, err := db.iterateRows(categoryName, q, cursor, pageSize+1)
+	if err != nil {
+		return nil, "", err
+	}
+	defer it.Close()
+
+	var result []RowData
+	var lastValues []interface{}
+	next := ""
+	for it.Next() {
+		if len(result) == pageSize {
+			next = encodeCursor(it.keys, lastValues)
+			break
+		}
+		result = append(result, it.Row())
+		lastValues = it.last
+	}
+	if err := it.Err(); err != nil {
+		return nil, "", err
+	}
+
+	return result, next, nil
+}
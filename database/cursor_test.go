@@ -0,0 +1,159 @@
+package database
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+)
+
+// rowIDs returns the ids of rows, in order
+func rowIDs(rows []RowData) []int {
+	ids := make([]int, len(rows))
+	for i, row := range rows {
+		ids[i] = row["id"].(int)
+	}
+	return ids
+}
+
+func TestListRowsAfterMatchesOffsetPaging(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	setupQueryTestCategory(t, db)
+	for i := 0; i < 3; i++ {
+		if _, err := db.CreateRow("Tasks", RowData{"Note": "Undated", "Project": "A", "Effort": 3}); err != nil {
+			t.Fatalf("Failed to create test row: %v", err)
+		}
+	}
+
+	queries := map[string]Query{
+		"default order":        {},
+		"ascending with null":  {Sort: []SortKey{{Column: "Due"}}},
+		"descending with null": {Sort: []SortKey{{Column: "Due", Desc: true}, {Column: "Effort"}}},
+		"filtered":             {Where: []Filter{{Column: "Project", Value: "A"}}, Sort: []SortKey{{Column: "Effort", Desc: true}}},
+		"created at":           {Sort: []SortKey{{Column: "created_at"}}},
+	}
+
+	for name, q := range queries {
+		t.Run(name, func(t *testing.T) {
+			all, _, err := db.ListRowsQuery("Tasks", q, 1, 100)
+			if err != nil {
+				t.Fatalf("ListRowsQuery() error = %v", err)
+			}
+
+			var paged []RowData
+			cursor := ""
+			for pages := 0; ; pages++ {
+				if pages > len(all) {
+					t.Fatal("ListRowsAfter() never returned an empty cursor")
+				}
+				rows, next, err := db.ListRowsAfter("Tasks", q, cursor, 2)
+				if err != nil {
+					t.Fatalf("ListRowsAfter() error = %v", err)
+				}
+				paged = append(paged, rows...)
+				if next == "" {
+					break
+				}
+				cursor = next
+			}
+
+			if !reflect.DeepEqual(rowIDs(paged), rowIDs(all)) {
+				t.Errorf("ListRowsAfter() ids = %v, want %v", rowIDs(paged), rowIDs(all))
+			}
+		})
+	}
+}
+
+func TestListRowsAfterRejectsForeignCursors(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	setupQueryTestCategory(t, db)
+
+	_, cursor, err := db.ListRowsAfter("Tasks", Query{}, "", 1)
+	if err != nil || cursor == "" {
+		t.Fatalf("ListRowsAfter() cursor = %q, error = %v", cursor, err)
+	}
+
+	if _, _, err := db.ListRowsAfter("Tasks", Query{Sort: []SortKey{{Column: "Effort"}}}, cursor, 1); err == nil {
+		t.Error("ListRowsAfter() accepted a cursor made for another sort order")
+	}
+	if _, _, err := db.ListRowsAfter("Tasks", Query{}, "not a cursor", 1); err == nil {
+		t.Error("ListRowsAfter() accepted an invalid cursor")
+	}
+	if _, _, err := db.ListRowsAfter("Tasks", Query{}, "", 0); err == nil {
+		t.Error("ListRowsAfter() accepted a zero page size")
+	}
+}
+
+func TestIterateRows(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	setupQueryTestCategory(t, db)
+
+	it, err := db.IterateRows("Tasks", Query{Sort: []SortKey{{Column: "Effort"}}}, "")
+	if err != nil {
+		t.Fatalf("IterateRows() error = %v", err)
+	}
+	defer it.Close()
+
+	if it.Cursor() != "" {
+		t.Error("Cursor() before Next() should be empty")
+	}
+
+	var efforts []int
+	resume := ""
+	for it.Next() {
+		efforts = append(efforts, it.Row()["Effort"].(int))
+		if len(efforts) == 2 {
+			resume = it.Cursor()
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	if !reflect.DeepEqual(efforts, []int{1, 2, 3, 5}) {
+		t.Errorf("IterateRows() efforts = %v, want [1 2 3 5]", efforts)
+	}
+
+	rest, err := db.IterateRows("Tasks", Query{Sort: []SortKey{{Column: "Effort"}}}, resume)
+	if err != nil {
+		t.Fatalf("IterateRows() from cursor error = %v", err)
+	}
+	defer rest.Close()
+	efforts = nil
+	for rest.Next() {
+		efforts = append(efforts, rest.Row()["Effort"].(int))
+	}
+	if !reflect.DeepEqual(efforts, []int{3, 5}) {
+		t.Errorf("IterateRows() from cursor efforts = %v, want [3 5]", efforts)
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	setupQueryTestCategory(t, db)
+
+	var buf bytes.Buffer
+	q := Query{Where: []Filter{{Column: "Project", Value: "A"}}, Sort: []SortKey{{Column: "id"}}}
+	if err := db.ExportCSV("Tasks", q, &buf); err != nil {
+		t.Fatalf("ExportCSV() error = %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read export: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("ExportCSV() wrote %d records, want a header and 2 rows", len(records))
+	}
+
+	want := []string{"id", "Note", "Project", "Due", "Effort"}
+	if !reflect.DeepEqual(records[0][:len(want)], want) {
+		t.Errorf("ExportCSV() header = %v, want it to start with %v", records[0], want)
+	}
+	if !reflect.DeepEqual(records[1][:len(want)], []string{"1", "Write report", "A", "2024-03-01 00:00:00", "3"}) {
+		t.Errorf("ExportCSV() first row = %v", records[1])
+	}
+}
@@ -0,0 +1,252 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// knownCompletions lists the completion values understood by the application
+var knownCompletions = map[string]bool{
+	LastCompletion:   true,
+	NoCompletion:     true,
+	UniqueCompletion: true,
+	SetCompletion:    true,
+	DateCompletion:   true,
+	FileCompletion:   true,
+}
+
+// knownSorts lists the completion sorts understood by the application
+var knownSorts = map[string]bool{
+	NoSort:         true,
+	FrequencySort:  true,
+	LastSort:       true,
+	alphabeticSort: true,
+}
+
+// knownChecks lists the checks understood by Datatype.ValidateCheck
+var knownChecks = map[string]bool{
+	nonemptyCheck:   true,
+	RangeCheck:      true,
+	SetCheck:        true,
+	NoCheck:         true,
+	URLCheck:        true,
+	MailCheck:       true,
+	PhoneCheck:      true,
+	FileCheck:       true,
+	DateCheck:       true,
+	RecurrenceCheck: true,
+	RegexCheck:      true,
+	MinLengthCheck:  true,
+	MaxLengthCheck:  true,
+	AndCheck:        true,
+	OrCheck:         true,
+	NotCheck:        true,
+}
+
+// validateCompletion checks a CompletionValue such as "unique" or "in(1,2,3)"
+func validateCompletion(completion string) error {
+	node, err := parseRule(completion)
+	if err != nil {
+		return fmt.Errorf("invalid completion %q: %w", completion, err)
+	}
+	if !knownCompletions[node.name] {
+		return fmt.Errorf("unknown completion %q", completion)
+	}
+	if node.name != SetCompletion {
+		if node.call {
+			return fmt.Errorf("completion %s takes no arguments", node.name)
+		}
+		return nil
+	}
+
+	if len(node.args) == 0 {
+		return fmt.Errorf("completion %s needs at least one value", SetCompletion)
+	}
+	for _, arg := range node.args {
+		if !arg.literal {
+			return fmt.Errorf("completion %s expects values, got %s", SetCompletion, arg)
+		}
+	}
+	return nil
+}
+
+// validateValueCheck checks a ValueCheck such as "nonempty", "range(1,10)" or "and(nonempty,max_length(20))"
+func validateValueCheck(check string) error {
+	_, err := compileCheck(check)
+	return err
+}
+
+// validateDatatypeSpec checks every field of a datatype before it is stored
+func validateDatatypeSpec(dt Datatype) error {
+	if err := validateIdentifier(dt.Name); err != nil {
+		return fmt.Errorf("invalid datatype name: %w", err)
+	}
+	if crudColumns[strings.ToLower(dt.Name)] {
+		return fmt.Errorf("datatype name %q is reserved", dt.Name)
+	}
+	if _, err := toSQLiteType(dt.VariableType); err != nil {
+		return err
+	}
+	if err := validateCompletion(dt.CompletionValue); err != nil {
+		return err
+	}
+	if !knownSorts[dt.CompletionSort] {
+		return fmt.Errorf("unknown completion sort %q", dt.CompletionSort)
+	}
+	if err := validateValueCheck(dt.ValueCheck); err != nil {
+		return err
+	}
+	if dt.FillBehavior != Open && dt.FillBehavior != Close {
+		return fmt.Errorf("unknown fill behavior %q", dt.FillBehavior)
+	}
+	return nil
+}
+
+// categoriesUsingColumn returns the category tables that contain a column with the given name
+func categoriesUsingColumn(tx *sql.Tx, column string) ([]string, error) {
+	rows, err := tx.Query(`
+		SELECT m.name
+		FROM sqlite_master AS m
+		JOIN pragma_table_info(m.name) AS p
+		WHERE m.type = 'table'
+		  AND m.name NOT LIKE 'sqlite_%'
+		  AND p.name = ? COLLATE NOCASE
+		ORDER BY m.name
+	`, column)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query column usage: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan table name: %w", err)
+		}
+		if isReservedTableName(name) {
+			continue
+		}
+		categories = append(categories, name)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return categories, nil
+}
+
+// CreateDatatype registers a new datatype and returns its ID
+func (db *Database) CreateDatatype(dt Datatype) (int, error) {
+	if err := validateDatatypeSpec(dt); err != nil {
+		return 0, fmt.Errorf("invalid datatype: %w", err)
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf(failedToBeginTxString, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := GetDatatypeByName(tx, dt.Name); err == nil {
+		return 0, fmt.Errorf("datatype %s already exists", dt.Name)
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO datatypes
+		(name, variable_type, completion_value, completion_sort, value_check, fill_behavior)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, dt.Name, dt.VariableType, dt.CompletionValue, dt.CompletionSort, dt.ValueCheck, dt.FillBehavior)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert datatype %s: %w", dt.Name, err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return int(id), nil
+}
+
+// UpdateDatatype replaces the specification of the datatype with the same name.
+// The variable type cannot change while a category uses the datatype.
+func (db *Database) UpdateDatatype(dt Datatype) error {
+	if err := validateDatatypeSpec(dt); err != nil {
+		return fmt.Errorf("invalid datatype: %w", err)
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf(failedToBeginTxString, err)
+	}
+	defer tx.Rollback()
+
+	current, err := GetDatatypeByName(tx, dt.Name)
+	if err != nil {
+		return err
+	}
+
+	if current.VariableType != dt.VariableType {
+		categories, err := categoriesUsingColumn(tx, dt.Name)
+		if err != nil {
+			return err
+		}
+		if len(categories) > 0 {
+			return fmt.Errorf("cannot change type of %s: used by %s", dt.Name, strings.Join(categories, ", "))
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE datatypes
+		SET variable_type = ?,
+		    completion_value = ?,
+		    completion_sort = ?,
+		    value_check = ?,
+		    fill_behavior = ?
+		WHERE id = ?
+	`, dt.VariableType, dt.CompletionValue, dt.CompletionSort, dt.ValueCheck, dt.FillBehavior, current.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update datatype %s: %w", dt.Name, err)
+	}
+
+	return tx.Commit()
+}
+
+// DeleteDatatype removes a datatype that no category uses anymore
+func (db *Database) DeleteDatatype(name string) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf(failedToBeginTxString, err)
+	}
+	defer tx.Rollback()
+
+	current, err := GetDatatypeByName(tx, name)
+	if err != nil {
+		return err
+	}
+
+	categories, err := categoriesUsingColumn(tx, current.Name)
+	if err != nil {
+		return err
+	}
+	if len(categories) > 0 {
+		return fmt.Errorf("cannot delete datatype %s: used by %s", current.Name, strings.Join(categories, ", "))
+	}
+
+	if _, err := tx.Exec("DELETE FROM datatypes WHERE id = ?", current.ID); err != nil {
+		return fmt.Errorf("failed to delete datatype %s: %w", current.Name, err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM datatype_overrides WHERE datatype = ?", current.Name); err != nil {
+		return fmt.Errorf("failed to delete overrides of %s: %w", current.Name, err)
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestCreateDatatype(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	valid := Datatype{Name: "Mood", VariableType: IntType, CompletionValue: SetCompletion + "(1,2,3)", CompletionSort: FrequencySort, ValueCheck: RangeCheck + "(1,3)", FillBehavior: Open}
+
+	tests := []struct {
+		name     string
+		datatype Datatype
+		wantErr  bool
+	}{
+		{name: "valid datatype", datatype: valid, wantErr: false},
+		{name: "duplicate name", datatype: valid, wantErr: true},
+		{
+			name:     "unknown variable type",
+			datatype: Datatype{Name: "Distance_km", VariableType: "decimal", CompletionValue: NoCompletion, CompletionSort: NoSort, ValueCheck: NoCheck, FillBehavior: Open},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown check",
+			datatype: Datatype{Name: "Distance_km", VariableType: FloatType, CompletionValue: NoCompletion, CompletionSort: NoSort, ValueCheck: "positive", FillBehavior: Open},
+			wantErr:  true,
+		},
+		{
+			name:     "malformed range",
+			datatype: Datatype{Name: "Distance_km", VariableType: IntType, CompletionValue: NoCompletion, CompletionSort: NoSort, ValueCheck: RangeCheck + "(10,1)", FillBehavior: Open},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown completion sort",
+			datatype: Datatype{Name: "Distance_km", VariableType: FloatType, CompletionValue: NoCompletion, CompletionSort: "random", ValueCheck: NoCheck, FillBehavior: Open},
+			wantErr:  true,
+		},
+		{
+			name:     "valid float datatype",
+			datatype: Datatype{Name: "Distance_km", VariableType: FloatType, CompletionValue: LastCompletion, CompletionSort: LastSort, ValueCheck: NoCheck, FillBehavior: Close},
+			wantErr:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := db.CreateDatatype(tt.datatype)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CreateDatatype() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpdateAndDeleteDatatype(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	// Note is used by General, so its type is frozen and it cannot be deleted
+	note := Datatype{Name: "Note", VariableType: StringType, CompletionValue: NoCompletion, CompletionSort: NoSort, ValueCheck: NoCheck, FillBehavior: Open}
+	if err := db.UpdateDatatype(note); err != nil {
+		t.Errorf("UpdateDatatype() error = %v", err)
+	}
+
+	note.VariableType = IntType
+	if err := db.UpdateDatatype(note); err == nil {
+		t.Errorf("UpdateDatatype() changed the type of a datatype in use")
+	}
+
+	if err := db.DeleteDatatype("Note"); err == nil {
+		t.Errorf("DeleteDatatype() deleted a datatype in use")
+	}
+
+	mood := Datatype{Name: "Mood", VariableType: StringType, CompletionValue: NoCompletion, CompletionSort: NoSort, ValueCheck: NoCheck, FillBehavior: Open}
+	if _, err := db.CreateDatatype(mood); err != nil {
+		t.Fatalf("Failed to create test datatype: %v", err)
+	}
+	if err := db.DeleteDatatype("Mood"); err != nil {
+		t.Errorf("DeleteDatatype() error = %v", err)
+	}
+	if err := db.DeleteDatatype("Mood"); err == nil {
+		t.Errorf("DeleteDatatype() deleted a missing datatype")
+	}
+}
+
+func TestDefaultDatatypesAreValid(t *testing.T) {
+	for _, dt := range getDefaultDatatypes() {
+		if err := validateDatatypeSpec(dt); err != nil {
+			t.Errorf("default datatype %s is invalid: %v", dt.Name, err)
+		}
+	}
+}
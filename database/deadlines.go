@@ -0,0 +1,105 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DueItem is a pending item with a deadline
+type DueItem struct {
+	Category string
+	ID       int
+	Deadline time.Time
+	Preview  string // Note and Project values, if the category has them
+}
+
+// DueItems returns the pending items whose Deadline is in [from, to), earliest first.
+// A zero from or to leaves that side of the range open.
+// Every category with a Deadline column is scanned.
+func (db *Database) DueItems(from, to time.Time) ([]DueItem, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf(failedToBeginTxString, err)
+	}
+	defer tx.Rollback()
+
+	categories, err := categoryTableNames(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Deadlines are stored as local wall clock text, which sorts chronologically
+	deadline := quoteIdentifier("Deadline")
+	condition := fmt.Sprintf("c.%s IS NOT NULL AND c.%s != ''", deadline, deadline)
+	var bounds []interface{}
+	if !from.IsZero() {
+		condition += fmt.Sprintf(" AND c.%s >= ?", deadline)
+		bounds = append(bounds, from.In(time.Local).Format(datetimeFormat))
+	}
+	if !to.IsZero() {
+		condition += fmt.Sprintf(" AND c.%s < ?", deadline)
+		bounds = append(bounds, to.In(time.Local).Format(datetimeFormat))
+	}
+
+	var items []DueItem
+	for _, category := range categories {
+		columns, err := getTableColumns(tx, category)
+		if err != nil {
+			return nil, err
+		}
+		if findColumn(columns, "Deadline") == -1 {
+			continue
+		}
+		table, err := quoteCategory(category)
+		if err != nil {
+			return nil, err
+		}
+		types, err := getColumnTypes(tx, category)
+		if err != nil {
+			return nil, err
+		}
+
+		query := fmt.Sprintf(`
+            SELECT c.*
+            FROM %s c
+            JOIN pending p
+              ON p.category = ? AND p.item_id = c.id AND p.deleted_at IS NULL
+            WHERE c.deleted_at IS NULL AND %s
+        `, table, condition)
+		rows, err := tx.Query(query, append([]interface{}{category}, bounds...)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query deadlines of %s: %w", category, err)
+		}
+		rowColumns, err := rows.Columns()
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf(columnsFetchErrorString, err)
+		}
+
+		for rows.Next() {
+			row, err := scanRow(rows, rowColumns)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			db.decodeRow(row, types)
+
+			due, ok := row["Deadline"].(time.Time)
+			if !ok {
+				continue
+			}
+			id, _ := row["id"].(int)
+			items = append(items, DueItem{Category: category, ID: id, Deadline: due, Preview: pendingPreview(row)})
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error iterating rows: %w", err)
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Deadline.Before(items[j].Deadline)
+	})
+	return items, nil
+}
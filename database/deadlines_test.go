@@ -0,0 +1,69 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDueItems(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	setupHabitsCategory(t, db)
+
+	rows := []RowData{
+		{"Opened": "2024-05-01 09:00", "Deadline": "2024-05-03 18:00", "Note": "Pay rent"},
+		{"Opened": "2024-05-01 09:00", "Deadline": "2024-05-10 12:00", "Note": "Dentist"},
+		{"Opened": "2024-05-01 09:00", "Deadline": "2024-05-05 08:00", "Note": "Send report"},
+		{"Opened": "2024-05-01 09:00", "Note": "No deadline"},
+		{"Opened": "2024-05-01 09:00", "Deadline": "2024-05-02 08:00", "Note": "Already done"},
+		{"Opened": "2024-05-01 09:00", "Deadline": "2024-05-04 08:00", "Note": "Deleted"},
+	}
+	for _, row := range rows {
+		if _, err := db.CreateRow("Habits", row); err != nil {
+			t.Fatalf("CreateRow() error = %v", err)
+		}
+	}
+	if _, err := db.CloseItem("Habits", 5, "2024-05-02 07:00:00"); err != nil {
+		t.Fatalf("CloseItem() error = %v", err)
+	}
+	if err := db.DeleteRow("Habits", 6); err != nil {
+		t.Fatalf("DeleteRow() error = %v", err)
+	}
+
+	now := time.Date(2024, 5, 4, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []int
+	}{
+		{name: "overdue", to: now, want: []int{1}},
+		{name: "upcoming day", from: now, to: now.Add(24 * time.Hour), want: []int{3}},
+		{name: "upcoming week", from: now, to: now.Add(7 * 24 * time.Hour), want: []int{3, 2}},
+		{name: "everything", want: []int{1, 3, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := db.DueItems(tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("DueItems() error = %v", err)
+			}
+			if len(items) != len(tt.want) {
+				t.Fatalf("DueItems() = %+v, want ids %v", items, tt.want)
+			}
+			for i, id := range tt.want {
+				if items[i].Category != "Habits" || items[i].ID != id {
+					t.Errorf("DueItems()[%d] = %+v, want Habits #%d", i, items[i], id)
+				}
+			}
+		})
+	}
+
+	items, err := db.DueItems(time.Time{}, now)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("DueItems() = %+v, %v", items, err)
+	}
+	if items[0].Preview != "Pay rent" || items[0].Deadline.Format(datetimeFormat) != "2024-05-03 18:00:00" {
+		t.Errorf("overdue item = %+v, want Pay rent due 2024-05-03 18:00:00", items[0])
+	}
+}
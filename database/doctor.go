@@ -0,0 +1,422 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Problem kinds reported by Doctor
+const (
+	ProblemOrphanPending    = "orphan pending"          // pending item whose row or category is gone
+	ProblemClosedPending    = "closed but pending"      // pending item that is no longer open
+	ProblemUntrackedOpen    = "open but untracked"      // open item missing from pending
+	ProblemDanglingColumn   = "column without datatype" // category column whose datatype was removed
+	ProblemDanglingOverride = "dangling override"       // override of a missing category or datatype
+	ProblemDanglingLink     = "dangling link"           // link from or to a row that no longer exists
+)
+
+// DoctorProblem is a single inconsistency found by Doctor
+type DoctorProblem struct {
+	Kind     string
+	Category string
+	ID       int // row id, 0 when the problem is not about a row
+	Detail   string
+	Fixed    bool
+}
+
+// DoctorReport lists the inconsistencies found by Doctor
+type DoctorReport struct {
+	Problems []DoctorProblem
+}
+
+// Fixed returns the number of problems that were repaired
+func (r DoctorReport) Fixed() int {
+	fixed := 0
+	for _, problem := range r.Problems {
+		if problem.Fixed {
+			fixed++
+		}
+	}
+	return fixed
+}
+
+// doctor collects problems and, when fixing, repairs them within the same transaction
+type doctor struct {
+	db     *Database
+	tx     *sql.Tx
+	fix    bool
+	report DoctorReport
+}
+
+// problem records a problem, applying repair when fixing. A nil repair cannot be fixed automatically.
+func (d *doctor) problem(p DoctorProblem, repair func() error) error {
+	if d.fix && repair != nil {
+		if err := repair(); err != nil {
+			return fmt.Errorf("failed to fix %s in %s: %w", p.Kind, p.Category, err)
+		}
+		p.Fixed = true
+	}
+	d.report.Problems = append(d.report.Problems, p)
+	return nil
+}
+
+// Doctor cross-checks the pending table, the category tables and the datatypes.
+// With fix set, every repairable problem is fixed in a single transaction.
+func (db *Database) Doctor(fix bool) (DoctorReport, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return DoctorReport{}, fmt.Errorf(failedToBeginTxString, err)
+	}
+	defer tx.Rollback()
+
+	d := &doctor{db: db, tx: tx, fix: fix}
+
+	categories, err := categoryTableNames(tx)
+	if err != nil {
+		return DoctorReport{}, err
+	}
+	registered := make(map[string]bool, len(categories))
+	for _, name := range categories {
+		exists, err := tableExists(tx, name)
+		if err != nil {
+			return DoctorReport{}, err
+		}
+		registered[name] = exists
+	}
+
+	datatypes, err := datatypeNames(tx)
+	if err != nil {
+		return DoctorReport{}, err
+	}
+
+	checks := []func() error{
+		func() error { return d.checkPending(registered) },
+		func() error { return d.checkUntracked(categories, registered) },
+		func() error { return d.checkColumns(categories, registered, datatypes) },
+		func() error { return d.checkOverrides(registered, datatypes) },
+		func() error { return d.checkLinks(registered) },
+	}
+	for _, check := range checks {
+		if err := check(); err != nil {
+			return DoctorReport{}, err
+		}
+	}
+
+	if fix {
+		if err := tx.Commit(); err != nil {
+			return DoctorReport{}, fmt.Errorf("failed to commit fixes: %w", err)
+		}
+	}
+	return d.report, nil
+}
+
+// datatypeNames returns the lower-cased names of every datatype
+func datatypeNames(tx *sql.Tx) (map[string]bool, error) {
+	rows, err := tx.Query("SELECT name FROM datatypes")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query datatypes: %w", err)
+	}
+	defer rows.Close()
+
+	names := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan datatype name: %w", err)
+		}
+		names[strings.ToLower(name)] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return names, nil
+}
+
+// activePending returns the category and id of every pending item
+func activePending(tx *sql.Tx) ([]PendingItem, error) {
+	rows, err := tx.Query(`
+        SELECT category, item_id
+        FROM pending
+        WHERE deleted_at IS NULL
+        ORDER BY category, item_id
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []PendingItem
+	for rows.Next() {
+		var item PendingItem
+		if err := rows.Scan(&item.Category, &item.ID); err != nil {
+			return nil, fmt.Errorf("failed to scan pending row: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return items, nil
+}
+
+// checkPending finds pending items whose row is missing, deleted or already closed
+func (d *doctor) checkPending(registered map[string]bool) error {
+	items, err := activePending(d.tx)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		category, id := item.Category, item.ID
+		forget := func() error { return d.db.deleteFromPending(d.tx, category, id) }
+
+		if !registered[category] {
+			err := d.problem(DoctorProblem{Kind: ProblemOrphanPending, Category: category, ID: id, Detail: "category does not exist"}, forget)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		table, err := quoteCategory(category)
+		if err != nil {
+			return err
+		}
+		var deleted bool
+		query := fmt.Sprintf("SELECT deleted_at IS NOT NULL FROM %s WHERE id = ?", table)
+		err = d.tx.QueryRow(query, id).Scan(&deleted)
+		if err == sql.ErrNoRows || deleted {
+			detail := "row does not exist"
+			if deleted {
+				detail = "row is deleted"
+			}
+			if err := d.problem(DoctorProblem{Kind: ProblemOrphanPending, Category: category, ID: id, Detail: detail}, forget); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read row %d of %s: %w", id, category, err)
+		}
+
+		open, err := isOpenItem(d.tx, category, id)
+		if err != nil {
+			return err
+		}
+		if !open {
+			stop := func() error { return d.db.removeFromPending(d.tx, category, id) }
+			if err := d.problem(DoctorProblem{Kind: ProblemClosedPending, Category: category, ID: id, Detail: "row is not open"}, stop); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkUntracked finds open rows that are missing from pending
+func (d *doctor) checkUntracked(categories []string, registered map[string]bool) error {
+	items, err := activePending(d.tx)
+	if err != nil {
+		return err
+	}
+	tracked := make(map[string]map[int]bool)
+	for _, item := range items {
+		if tracked[item.Category] == nil {
+			tracked[item.Category] = make(map[int]bool)
+		}
+		tracked[item.Category][item.ID] = true
+	}
+
+	for _, category := range categories {
+		if !registered[category] {
+			continue
+		}
+		columns, err := getTableColumns(d.tx, category)
+		if err != nil {
+			return err
+		}
+		condition, ok := openItemCondition(columns)
+		if !ok {
+			continue
+		}
+		table, err := quoteCategory(category)
+		if err != nil {
+			return err
+		}
+
+		query := fmt.Sprintf("SELECT id FROM %s WHERE deleted_at IS NULL AND %s ORDER BY id", table, condition)
+		ids, err := queryIDs(d.tx, query)
+		if err != nil {
+			return fmt.Errorf("failed to query open items of %s: %w", category, err)
+		}
+
+		for _, id := range ids {
+			if tracked[category][id] {
+				continue
+			}
+			id := id
+			track := func() error { return d.db.addToPending(d.tx, category, id) }
+			if err := d.problem(DoctorProblem{Kind: ProblemUntrackedOpen, Category: category, ID: id, Detail: "row is open"}, track); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// queryIDs returns the integer ids selected by query
+func queryIDs(tx *sql.Tx, query string, args ...interface{}) ([]int, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// checkColumns finds category columns without a datatype. They cannot be repaired automatically.
+func (d *doctor) checkColumns(categories []string, registered map[string]bool, datatypes map[string]bool) error {
+	for _, category := range categories {
+		if !registered[category] {
+			continue
+		}
+		columns, err := getTableColumns(d.tx, category)
+		if err != nil {
+			return err
+		}
+		for _, col := range columns {
+			if datatypes[strings.ToLower(col.Name)] {
+				continue
+			}
+			detail := fmt.Sprintf("column %s has no datatype", col.Name)
+			if err := d.problem(DoctorProblem{Kind: ProblemDanglingColumn, Category: category, Detail: detail}, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkOverrides finds datatype overrides of categories or datatypes that no longer exist
+func (d *doctor) checkOverrides(registered map[string]bool, datatypes map[string]bool) error {
+	rows, err := d.tx.Query("SELECT id, category, datatype FROM datatype_overrides ORDER BY id")
+	if err != nil {
+		return fmt.Errorf("failed to query datatype overrides: %w", err)
+	}
+
+	type override struct {
+		id                 int
+		category, datatype string
+	}
+	var overrides []override
+	for rows.Next() {
+		var o override
+		if err := rows.Scan(&o.id, &o.category, &o.datatype); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan datatype override: %w", err)
+		}
+		overrides = append(overrides, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	for _, o := range overrides {
+		var detail string
+		switch {
+		case !registered[o.category]:
+			detail = fmt.Sprintf("override of %s for a missing category", o.datatype)
+		case !datatypes[strings.ToLower(o.datatype)]:
+			detail = fmt.Sprintf("override of missing datatype %s", o.datatype)
+		default:
+			continue
+		}
+
+		id := o.id
+		drop := func() error {
+			_, err := d.tx.Exec("DELETE FROM datatype_overrides WHERE id = ?", id)
+			return err
+		}
+		if err := d.problem(DoctorProblem{Kind: ProblemDanglingOverride, Category: o.category, Detail: detail}, drop); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rowExists reports whether a row is stored in a category, deleted or not
+func rowExists(tx *sql.Tx, item ItemRef) (bool, error) {
+	table, err := quoteCategory(item.Category)
+	if err != nil {
+		return false, err
+	}
+	var count int
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE id = ?", table)
+	if err := tx.QueryRow(query, item.ID).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to look up item %s: %w", item, err)
+	}
+	return count > 0, nil
+}
+
+// checkLinks finds links whose source or target row is gone. Links of deleted rows are kept for RestoreRow.
+func (d *doctor) checkLinks(registered map[string]bool) error {
+	rows, err := d.tx.Query("SELECT id, source_category, source_id, target_category, target_id FROM links ORDER BY id")
+	if err != nil {
+		return fmt.Errorf("failed to query links: %w", err)
+	}
+
+	type link struct {
+		id             int
+		source, target ItemRef
+	}
+	var links []link
+	for rows.Next() {
+		var l link
+		if err := rows.Scan(&l.id, &l.source.Category, &l.source.ID, &l.target.Category, &l.target.ID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan link: %w", err)
+		}
+		links = append(links, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	for _, l := range links {
+		for _, end := range []ItemRef{l.source, l.target} {
+			exists := registered[end.Category]
+			if exists {
+				if exists, err = rowExists(d.tx, end); err != nil {
+					return err
+				}
+			}
+			if exists {
+				continue
+			}
+
+			id := l.id
+			drop := func() error {
+				_, err := d.tx.Exec("DELETE FROM links WHERE id = ?", id)
+				return err
+			}
+			detail := fmt.Sprintf("link from %s to %s points to a missing row", l.source, l.target)
+			if err := d.problem(DoctorProblem{Kind: ProblemDanglingLink, Category: end.Category, ID: end.ID, Detail: detail}, drop); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"testing"
+)
+
+// problemKinds counts the reported problems by kind
+func problemKinds(report DoctorReport) map[string]int {
+	kinds := make(map[string]int)
+	for _, problem := range report.Problems {
+		kinds[problem.Kind]++
+	}
+	return kinds
+}
+
+func TestDoctor(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	setupChoresCategory(t, db)
+
+	for _, note := range []string{"Healthy", "Untracked", "Closed behind our back"} {
+		if _, err := db.CreateRow("Chores", RowData{"Opened": "2024-05-01 09:00", "Note": note}); err != nil {
+			t.Fatalf("CreateRow() error = %v", err)
+		}
+	}
+
+	// Break the bookkeeping the way older versions or manual edits could
+	statements := []string{
+		"UPDATE pending SET deleted_at = CURRENT_TIMESTAMP WHERE category = 'Chores' AND item_id = 2",
+		"UPDATE Chores SET Closed = '2024-05-02 10:00:00' WHERE id = 3",
+		"INSERT INTO pending (category, item_id) VALUES ('Chores', 99), ('Ghost', 1)",
+		"INSERT INTO datatype_overrides (category, datatype, value_check) VALUES ('Chores', 'Vanished', 'nonempty')",
+		"ALTER TABLE Chores ADD COLUMN Mystery TEXT",
+		"INSERT INTO links (source_category, source_id, target_category, target_id, relation) VALUES ('Chores', 1, 'Chores', 42, 'related')",
+	}
+	for _, statement := range statements {
+		if _, err := db.DB.Exec(statement); err != nil {
+			t.Fatalf("failed to run %q: %v", statement, err)
+		}
+	}
+
+	want := map[string]int{
+		ProblemOrphanPending:    2,
+		ProblemClosedPending:    1,
+		ProblemUntrackedOpen:    1,
+		ProblemDanglingColumn:   1,
+		ProblemDanglingOverride: 1,
+		ProblemDanglingLink:     1,
+	}
+
+	// A dry run reports without changing anything
+	report, err := db.Doctor(false)
+	if err != nil {
+		t.Fatalf("Doctor(false) error = %v", err)
+	}
+	got := problemKinds(report)
+	for kind, count := range want {
+		if got[kind] != count {
+			t.Errorf("Doctor(false) found %d %q problems, want %d (report %+v)", got[kind], kind, count, report.Problems)
+		}
+	}
+	if report.Fixed() != 0 {
+		t.Errorf("Doctor(false) fixed %d problems, want none", report.Fixed())
+	}
+	if len(pendingPointers(t, db)) != 4 {
+		t.Errorf("Doctor(false) changed the pending items: %v", pendingPointers(t, db))
+	}
+
+	// Fixing repairs everything but the column, which needs a decision from the user
+	report, err = db.Doctor(true)
+	if err != nil {
+		t.Fatalf("Doctor(true) error = %v", err)
+	}
+	if report.Fixed() != len(report.Problems)-1 {
+		t.Errorf("Doctor(true) fixed %d of %d problems, want all but one", report.Fixed(), len(report.Problems))
+	}
+
+	pointers := pendingPointers(t, db)
+	if len(pointers) != 2 || !pointers["Chores:1"] || !pointers["Chores:2"] {
+		t.Errorf("pending after fix = %v, want Chores:1 and Chores:2", pointers)
+	}
+
+	report, err = db.Doctor(false)
+	if err != nil {
+		t.Fatalf("Doctor(false) after fix error = %v", err)
+	}
+	if got := problemKinds(report); len(report.Problems) != 1 || got[ProblemDanglingColumn] != 1 {
+		t.Errorf("Doctor(false) after fix = %+v, want only the dangling column", report.Problems)
+	}
+}
+
+func TestCloseItemRejectsClosedItem(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	setupChoresCategory(t, db)
+
+	if _, err := db.CreateRow("Chores", RowData{"Opened": "2024-05-01 09:00", "Note": "Once"}); err != nil {
+		t.Fatalf("CreateRow() error = %v", err)
+	}
+	if _, err := db.CloseItem("Chores", 1, "2024-05-02 10:00:00"); err != nil {
+		t.Fatalf("CloseItem() error = %v", err)
+	}
+	if _, err := db.CloseItem("Chores", 1, "2024-05-03 10:00:00"); err == nil {
+		t.Errorf("CloseItem() closed an item twice")
+	}
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// exportValue formats a decoded value for a CSV cell
+func exportValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(datetimeFormat)
+	case []string:
+		return joinCSV(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// ExportCSV writes the rows of a category matching a query as CSV, with a header row.
+// Rows are streamed, so the whole category is never held in memory.
+func (db *Database) ExportCSV(categoryName string, q Query, w io.Writer) error {
+	columns, err := db.GetCategoryColumns(categoryName)
+	if err != nil {
+		return fmt.Errorf(columnsFetchErrorString, err)
+	}
+	header := append([]string{"id"}, columns...)
+	header = append(header, "created_at", "updated_at")
+
+	it, err := db.IterateRows(categoryName, q, "")
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+
+	record := make([]string, len(header))
+	for it.Next() {
+		row := it.Row()
+		for i, col := range header {
+			record[i] = exportValue(row[col])
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write row: %w", err)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
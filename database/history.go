@@ -0,0 +1,318 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// History operations
+const (
+	HistoryCreate  = "create"
+	HistoryUpdate  = "update"
+	HistoryClose   = "close"
+	HistoryDelete  = "delete"
+	HistoryRestore = "restore"
+	HistoryRevert  = "revert"
+	HistoryPurge   = "purge" // the row was removed for good, by PurgeDeleted or DeleteCategory
+)
+
+// HistoryEntry is a recorded change of a row.
+// OldValues is nil for creations and NewValues is nil for purges.
+type HistoryEntry struct {
+	ID        int
+	CreatedAt time.Time
+	Operation string
+	Category  string
+	ItemID    int
+	OldValues RowData
+	NewValues RowData
+}
+
+// createHistoryTable creates the append-only log of row changes
+func createHistoryTable(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+        CREATE TABLE IF NOT EXISTS history (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+            operation TEXT NOT NULL,
+            category TEXT NOT NULL,
+            item_id INTEGER NOT NULL,
+            old_values TEXT DEFAULT NULL,
+            new_values TEXT DEFAULT NULL
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create history table: %w", err)
+	}
+
+	_, err = tx.Exec(`CREATE INDEX IF NOT EXISTS idx_history_item ON history(category, item_id)`)
+	if err != nil {
+		return fmt.Errorf("failed to create history index: %w", err)
+	}
+	return nil
+}
+
+// rowSnapshot returns the stored values of a row's own columns, deleted or not
+func rowSnapshot(tx *sql.Tx, categoryName string, id int) (RowData, error) {
+	table, err := quoteCategory(categoryName)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(fmt.Sprintf("SELECT * FROM %s WHERE id = ?", table), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read row %d of %s: %w", id, categoryName, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf(columnsFetchErrorString, err)
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error iterating rows: %w", err)
+		}
+		return nil, sql.ErrNoRows
+	}
+
+	row, err := scanRow(rows, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := make(RowData, len(row))
+	for col, raw := range row {
+		if crudColumns[col] {
+			continue
+		}
+		snapshot[col] = storedValue(raw)
+	}
+	return snapshot, nil
+}
+
+// marshalSnapshot encodes a snapshot for the history table, nil staying NULL
+func marshalSnapshot(snapshot RowData) (interface{}, error) {
+	if snapshot == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode row snapshot: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// unmarshalSnapshot decodes a snapshot stored by marshalSnapshot
+func unmarshalSnapshot(stored sql.NullString) (RowData, error) {
+	if !stored.Valid {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(stored.String))
+	decoder.UseNumber()
+	var snapshot RowData
+	if err := decoder.Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode row snapshot: %w", err)
+	}
+	for col, value := range snapshot {
+		snapshot[col] = fromJSONNumber(value)
+	}
+	return snapshot, nil
+}
+
+// recordHistory appends a change of a row to the history
+func recordHistory(tx *sql.Tx, operation, categoryName string, id int, oldValues, newValues RowData) error {
+	oldJSON, err := marshalSnapshot(oldValues)
+	if err != nil {
+		return err
+	}
+	newJSON, err := marshalSnapshot(newValues)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO history (operation, category, item_id, old_values, new_values)
+		VALUES (?, ?, ?, ?, ?)
+	`, operation, categoryName, id, oldJSON, newJSON)
+	if err != nil {
+		return fmt.Errorf("failed to record %s of row %d in %s: %w", operation, id, categoryName, err)
+	}
+	return nil
+}
+
+// recordChange records a change of a row, reading its values after the change
+func recordChange(tx *sql.Tx, operation, categoryName string, id int, oldValues RowData) error {
+	newValues, err := rowSnapshot(tx, categoryName, id)
+	if err != nil {
+		return err
+	}
+	return recordHistory(tx, operation, categoryName, id, oldValues, newValues)
+}
+
+// getHistoryEntry reads a single history entry, with its values as stored
+func getHistoryEntry(tx *sql.Tx, historyID int) (*HistoryEntry, error) {
+	var entry HistoryEntry
+	var oldJSON, newJSON sql.NullString
+	err := tx.QueryRow(`
+		SELECT id, created_at, operation, category, item_id, old_values, new_values
+		FROM history
+		WHERE id = ?
+	`, historyID).Scan(&entry.ID, &entry.CreatedAt, &entry.Operation, &entry.Category, &entry.ItemID, &oldJSON, &newJSON)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no history entry with id %d", historyID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history entry %d: %w", historyID, err)
+	}
+
+	if entry.OldValues, err = unmarshalSnapshot(oldJSON); err != nil {
+		return nil, err
+	}
+	if entry.NewValues, err = unmarshalSnapshot(newJSON); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// RowHistory returns the recorded changes of a row, oldest first,
+// with values converted to their declared types
+func (db *Database) RowHistory(categoryName string, id int) ([]HistoryEntry, error) {
+	rows, err := db.DB.Query(`
+		SELECT id, created_at, operation, category, item_id, old_values, new_values
+		FROM history
+		WHERE category = ? AND item_id = ?
+		ORDER BY id
+	`, categoryName, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []HistoryEntry
+	for rows.Next() {
+		var entry HistoryEntry
+		var oldJSON, newJSON sql.NullString
+		if err := rows.Scan(&entry.ID, &entry.CreatedAt, &entry.Operation, &entry.Category, &entry.ItemID, &oldJSON, &newJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan history entry: %w", err)
+		}
+		if entry.OldValues, err = unmarshalSnapshot(oldJSON); err != nil {
+			return nil, err
+		}
+		if entry.NewValues, err = unmarshalSnapshot(newJSON); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	rows.Close()
+
+	types, err := getColumnTypes(db.DB, categoryName)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.OldValues != nil {
+			db.decodeRow(entry.OldValues, types)
+		}
+		if entry.NewValues != nil {
+			db.decodeRow(entry.NewValues, types)
+		}
+	}
+	return entries, nil
+}
+
+// LatestHistoryID returns the id of the most recent change recorded for a row
+func (db *Database) LatestHistoryID(categoryName string, id int) (int, error) {
+	var historyID sql.NullInt64
+	err := db.DB.QueryRow(`
+		SELECT MAX(id)
+		FROM history
+		WHERE category = ? AND item_id = ?
+	`, categoryName, id).Scan(&historyID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest change of row %d of %s: %w", id, categoryName, err)
+	}
+	if !historyID.Valid {
+		return 0, fmt.Errorf("no recorded change for row %d of %s", id, categoryName)
+	}
+	return int(historyID.Int64), nil
+}
+
+// RevertRow undoes a recorded change, restoring the values the row had before it.
+// Deleted rows are restored as well. Columns removed since the change are skipped.
+// The revert is itself recorded in the history.
+func (db *Database) RevertRow(categoryName string, id int, historyID int) error {
+	table, err := quoteCategory(categoryName)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf(failedToBeginTxString, err)
+	}
+	defer tx.Rollback()
+
+	entry, err := getHistoryEntry(tx, historyID)
+	if err != nil {
+		return err
+	}
+	if entry.Category != categoryName || entry.ItemID != id {
+		return fmt.Errorf("history entry %d does not belong to item %d of %s", historyID, id, categoryName)
+	}
+	if entry.OldValues == nil {
+		return fmt.Errorf("history entry %d records the creation of the item, delete it instead", historyID)
+	}
+	if entry.Operation == HistoryPurge {
+		return fmt.Errorf("history entry %d records a purge, the item no longer exists", historyID)
+	}
+
+	current, err := rowSnapshot(tx, categoryName, id)
+	if err != nil {
+		return err
+	}
+
+	columns, err := getTableColumns(tx, categoryName)
+	if err != nil {
+		return err
+	}
+
+	setClauses := []string{"deleted_at = NULL", "updated_at = CURRENT_TIMESTAMP"}
+	values := make([]interface{}, 0, len(columns)+1)
+	for _, col := range columns {
+		value, ok := entry.OldValues[col.Name]
+		if !ok {
+			continue // the column did not exist at the time
+		}
+		setClauses = append(setClauses, fmt.Sprintf("%s = ?", quoteIdentifier(col.Name)))
+		values = append(values, value)
+	}
+	values = append(values, id)
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE id = ?", table, strings.Join(setClauses, ", "))
+	if _, err := tx.Exec(query, values...); err != nil {
+		return fmt.Errorf("failed to revert row: %w", err)
+	}
+
+	// Keep pending tracking in line with the reverted values
+	if err := db.syncPending(tx, categoryName, id); err != nil {
+		return err
+	}
+
+	if err := indexRow(tx, categoryName, id); err != nil {
+		return err
+	}
+
+	if err := recordChange(tx, HistoryRevert, categoryName, id, current); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRowHistoryRecordsEveryChange(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	setupChoresCategory(t, db)
+
+	if _, err := db.CreateRow("Chores", RowData{"Opened": "2024-05-01 09:00", "Note": "Paint fence"}); err != nil {
+		t.Fatalf("CreateRow() error = %v", err)
+	}
+	if err := db.UpdateRow("Chores", 1, RowData{"Note": "Paint the fence"}); err != nil {
+		t.Fatalf("UpdateRow() error = %v", err)
+	}
+	if _, err := db.CloseItem("Chores", 1, "2024-05-03 18:00:00"); err != nil {
+		t.Fatalf("CloseItem() error = %v", err)
+	}
+	if err := db.DeleteRow("Chores", 1); err != nil {
+		t.Fatalf("DeleteRow() error = %v", err)
+	}
+	if err := db.RestoreRow("Chores", 1); err != nil {
+		t.Fatalf("RestoreRow() error = %v", err)
+	}
+
+	entries, err := db.RowHistory("Chores", 1)
+	if err != nil {
+		t.Fatalf("RowHistory() error = %v", err)
+	}
+
+	operations := make([]string, len(entries))
+	for i, entry := range entries {
+		operations[i] = entry.Operation
+		if entry.Category != "Chores" || entry.ItemID != 1 || entry.CreatedAt.IsZero() {
+			t.Errorf("RowHistory() entry = %+v, want Chores item 1 with a timestamp", entry)
+		}
+	}
+	want := []string{HistoryCreate, HistoryUpdate, HistoryClose, HistoryDelete, HistoryRestore}
+	if !reflect.DeepEqual(operations, want) {
+		t.Fatalf("RowHistory() operations = %v, want %v", operations, want)
+	}
+
+	if entries[0].OldValues != nil || entries[0].NewValues["Note"] != "Paint fence" {
+		t.Errorf("create entry = %+v, want only new values", entries[0])
+	}
+	if entries[1].OldValues["Note"] != "Paint fence" || entries[1].NewValues["Note"] != "Paint the fence" {
+		t.Errorf("update entry = %+v, want old and new notes", entries[1])
+	}
+	if entries[2].OldValues["Closed"] != nil || entries[2].NewValues["Closed"] == nil {
+		t.Errorf("close entry = %+v, want the close time in the new values", entries[2])
+	}
+	if entries[3].NewValues != nil {
+		t.Errorf("delete entry = %+v, want no new values", entries[3])
+	}
+}
+
+func TestRevertRow(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	setupChoresCategory(t, db)
+
+	if _, err := db.CreateRow("Chores", RowData{"Opened": "2024-05-01 09:00", "Note": "Clean gutters"}); err != nil {
+		t.Fatalf("CreateRow() error = %v", err)
+	}
+	if _, err := db.CloseItem("Chores", 1, "2024-05-02 10:00:00"); err != nil {
+		t.Fatalf("CloseItem() error = %v", err)
+	}
+	if err := db.UpdateRow("Chores", 1, RowData{"Note": "Gutters done"}); err != nil {
+		t.Fatalf("UpdateRow() error = %v", err)
+	}
+	if err := db.DeleteRow("Chores", 1); err != nil {
+		t.Fatalf("DeleteRow() error = %v", err)
+	}
+
+	entries, err := db.RowHistory("Chores", 1)
+	if err != nil || len(entries) != 4 {
+		t.Fatalf("RowHistory() = %+v, %v, want 4 entries", entries, err)
+	}
+	closeEntry := entries[1]
+
+	if err := db.RevertRow("Chores", 1, entries[0].ID); err == nil {
+		t.Error("RevertRow() reverted the creation of the item")
+	}
+	if err := db.RevertRow("General", 1, closeEntry.ID); err == nil {
+		t.Error("RevertRow() accepted a history entry of another item")
+	}
+
+	// Undoing the close brings back the deleted row as it was before closing
+	if err := db.RevertRow("Chores", 1, closeEntry.ID); err != nil {
+		t.Fatalf("RevertRow() error = %v", err)
+	}
+	row, err := db.ReadRow("Chores", 1)
+	if err != nil {
+		t.Fatalf("ReadRow() error = %v", err)
+	}
+	if row["Note"] != "Clean gutters" || row["Closed"] != nil {
+		t.Errorf("ReadRow() = %v, want the note and no close time", row)
+	}
+	if !pendingPointers(t, db)["Chores:1"] {
+		t.Error("RevertRow() did not track the reopened item as pending")
+	}
+
+	entries, err = db.RowHistory("Chores", 1)
+	if err != nil || len(entries) != 5 || entries[4].Operation != HistoryRevert {
+		t.Fatalf("RowHistory() = %+v, %v, want the revert recorded", entries, err)
+	}
+}
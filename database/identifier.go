@@ -0,0 +1,57 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxIdentifierLength bounds the length of category and column names
+const maxIdentifierLength = 64
+
+// validateIdentifier checks that a name can be used as a table or column name.
+// Any printable text is accepted, since names are always quoted with quoteIdentifier.
+func validateIdentifier(name string) error {
+	if name == "" {
+		return fmt.Errorf("name is empty")
+	}
+	if !utf8.ValidString(name) {
+		return fmt.Errorf("name %q is not valid UTF-8", name)
+	}
+	if utf8.RuneCountInString(name) > maxIdentifierLength {
+		return fmt.Errorf("name %q is longer than %d characters", name, maxIdentifierLength)
+	}
+	if strings.TrimSpace(name) != name {
+		return fmt.Errorf("name %q starts or ends with whitespace", name)
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("name %q contains control characters", name)
+		}
+	}
+	return nil
+}
+
+// quoteIdentifier quotes a table or column name for use in generated SQL.
+// Embedded double quotes are doubled, as required by SQLite.
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteIdentifiers quotes every name in the list
+func quoteIdentifiers(names []string) []string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteIdentifier(name)
+	}
+	return quoted
+}
+
+// quoteCategory validates a category name and returns it quoted for generated SQL
+func quoteCategory(name string) (string, error) {
+	if err := validateCategoryName(name); err != nil {
+		return "", err
+	}
+	return quoteIdentifier(name), nil
+}
@@ -0,0 +1,125 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateIdentifier(t *testing.T) {
+	tests := []struct {
+		name       string
+		identifier string
+		wantErr    bool
+	}{
+		{name: "plain", identifier: "General", wantErr: false},
+		{name: "spaces", identifier: "Reading List", wantErr: false},
+		{name: "quotes", identifier: `Bob's "Books"`, wantErr: false},
+		{name: "unicode", identifier: "Café ☕", wantErr: false},
+		{name: "reserved word", identifier: "Order", wantErr: false},
+		{name: "empty", identifier: "", wantErr: true},
+		{name: "surrounding whitespace", identifier: " General ", wantErr: true},
+		{name: "control characters", identifier: "Gen\x00eral", wantErr: true},
+		{name: "newline", identifier: "Gen\neral", wantErr: true},
+		{name: "invalid utf8", identifier: "Gen\xffral", wantErr: true},
+		{name: "too long", identifier: strings.Repeat("a", maxIdentifierLength+1), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateIdentifier(tt.identifier)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateIdentifier(%q) error = %v, wantErr %v", tt.identifier, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	tests := []struct {
+		identifier string
+		want       string
+	}{
+		{identifier: "General", want: `"General"`},
+		{identifier: "Reading List", want: `"Reading List"`},
+		{identifier: `Bob's "Books"`, want: `"Bob's ""Books"""`},
+		{identifier: "Café ☕", want: `"Café ☕"`},
+	}
+
+	for _, tt := range tests {
+		if got := quoteIdentifier(tt.identifier); got != tt.want {
+			t.Errorf("quoteIdentifier(%q) = %s, want %s", tt.identifier, got, tt.want)
+		}
+	}
+}
+
+func TestUnusualCategoryNames(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	names := []string{"Reading List", `Bob's "Books"`, "Café ☕", "Order", "select"}
+
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			if err := db.CreateCategory(CategoryTemplate{Name: name, ColumnsID: []int{1, 2}}); err != nil {
+				t.Fatalf("CreateCategory() error = %v", err)
+			}
+			if _, err := db.CreateRow(name, RowData{"Note": "First", "Project": "A"}); err != nil {
+				t.Fatalf("CreateRow() error = %v", err)
+			}
+			if err := db.UpdateRow(name, 1, RowData{"Note": "Updated"}); err != nil {
+				t.Fatalf("UpdateRow() error = %v", err)
+			}
+
+			row, err := db.ReadRow(name, 1)
+			if err != nil {
+				t.Fatalf("ReadRow() error = %v", err)
+			}
+			if row["Note"] != "Updated" {
+				t.Errorf("ReadRow() Note = %v, want Updated", row["Note"])
+			}
+
+			rows, total, err := db.ListRows(name, RowData{"Project": "A"}, 1, 10)
+			if err != nil || total != 1 || len(rows) != 1 {
+				t.Errorf("ListRows() = %d rows, total %d, error %v, want 1 row", len(rows), total, err)
+			}
+
+			if err := db.AddColumn(name, "Location"); err != nil {
+				t.Errorf("AddColumn() error = %v", err)
+			}
+			if err := db.RemoveColumn(name, "Project"); err != nil {
+				t.Errorf("RemoveColumn() error = %v", err)
+			}
+			if err := db.DeleteRow(name, 1); err != nil {
+				t.Errorf("DeleteRow() error = %v", err)
+			}
+			if err := db.DeleteCategory(name); err != nil {
+				t.Errorf("DeleteCategory() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestListRowsRejectsUnknownFilterColumns(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	if _, err := db.CreateRow("General", RowData{"Note": "Secret", "Project": "A", "Location": "X"}); err != nil {
+		t.Fatalf("Failed to create test row: %v", err)
+	}
+
+	filters := []RowData{
+		{"Note = 'x' OR 1=1 --": "x"},
+		{`Note" = "Note`: "x"},
+		{"deleted_at": nil},
+	}
+
+	for _, filter := range filters {
+		if _, _, err := db.ListRows("General", filter, 1, 10); err == nil {
+			t.Errorf("ListRows() accepted filter %v", filter)
+		}
+	}
+
+	if _, _, err := db.ListRows("datatypes", RowData{}, 1, 10); err == nil {
+		t.Errorf("ListRows() listed a reserved table")
+	}
+}
@@ -0,0 +1,349 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Link relations
+const (
+	LinkDependsOn = "depends_on" // the source can only be closed after the target, CloseItem enforces it
+	LinkBelongsTo = "belongs_to" // the source is part of the target, e.g. an expense of a trip
+	LinkRelated   = "related"
+)
+
+var knownRelations = map[string]bool{
+	LinkDependsOn: true,
+	LinkBelongsTo: true,
+	LinkRelated:   true,
+}
+
+// ErrDependencyCycle is returned when a dependency link would make an item depend on itself
+var ErrDependencyCycle = errors.New("link would create a dependency cycle")
+
+// ErrBlocked is returned when closing an item that depends on items which are still pending
+var ErrBlocked = errors.New("item depends on pending items")
+
+// ItemRef identifies a row of a category
+type ItemRef struct {
+	Category string
+	ID       int
+}
+
+func (r ItemRef) String() string {
+	return fmt.Sprintf("%s:%d", r.Category, r.ID)
+}
+
+// Link is a relation from a source row to a target row
+type Link struct {
+	ID        int
+	CreatedAt time.Time
+	Source    ItemRef
+	Target    ItemRef
+	Relation  string
+}
+
+// BlockedItem is an open item with dependencies that are still pending
+type BlockedItem struct {
+	Item      ItemRef
+	BlockedBy []ItemRef
+}
+
+// createLinksTable creates the table of relations between rows
+func createLinksTable(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+        CREATE TABLE IF NOT EXISTS links (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+            source_category TEXT NOT NULL,
+            source_id INTEGER NOT NULL,
+            target_category TEXT NOT NULL,
+            target_id INTEGER NOT NULL,
+            relation TEXT NOT NULL,
+            UNIQUE (source_category, source_id, target_category, target_id, relation)
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create links table: %w", err)
+	}
+
+	_, err = tx.Exec(`CREATE INDEX IF NOT EXISTS idx_links_target ON links(target_category, target_id)`)
+	if err != nil {
+		return fmt.Errorf("failed to create links index: %w", err)
+	}
+	return nil
+}
+
+// resolveItem returns the item with its category named as stored, or an error unless the row
+// exists and is not deleted. Links compare category names exactly, so they store one spelling.
+func resolveItem(tx *sql.Tx, item ItemRef) (ItemRef, error) {
+	if err := checkCategoryExists(tx, item.Category); err != nil {
+		return ItemRef{}, err
+	}
+	stored, err := storedTableName(tx, item.Category)
+	if err != nil {
+		return ItemRef{}, err
+	}
+	item.Category = stored
+	table, err := quoteCategory(item.Category)
+	if err != nil {
+		return ItemRef{}, err
+	}
+
+	var count int
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE id = ? AND deleted_at IS NULL", table)
+	if err := tx.QueryRow(query, item.ID).Scan(&count); err != nil {
+		return ItemRef{}, fmt.Errorf("failed to look up item %s: %w", item, err)
+	}
+	if count == 0 {
+		return ItemRef{}, fmt.Errorf("no item found with id %d in category %s", item.ID, item.Category)
+	}
+	return item, nil
+}
+
+// dependsOn reports whether from reaches to by following dependency links
+func dependsOn(tx *sql.Tx, from, to ItemRef) (bool, error) {
+	visited := map[ItemRef]bool{from: true}
+	queue := []ItemRef{from}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		rows, err := tx.Query(`
+            SELECT target_category, target_id
+            FROM links
+            WHERE source_category = ? AND source_id = ? AND relation = ?
+        `, current.Category, current.ID, LinkDependsOn)
+		if err != nil {
+			return false, fmt.Errorf("failed to query dependencies of %s: %w", current, err)
+		}
+
+		var next []ItemRef
+		for rows.Next() {
+			var target ItemRef
+			if err := rows.Scan(&target.Category, &target.ID); err != nil {
+				rows.Close()
+				return false, fmt.Errorf("failed to scan dependency: %w", err)
+			}
+			next = append(next, target)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return false, fmt.Errorf("error iterating rows: %w", err)
+		}
+
+		for _, target := range next {
+			if target == to {
+				return true, nil
+			}
+			if !visited[target] {
+				visited[target] = true
+				queue = append(queue, target)
+			}
+		}
+	}
+	return false, nil
+}
+
+// Link relates the source row to the target row. Linking twice is not an error.
+// Dependency links that would close a cycle are refused with ErrDependencyCycle.
+func (db *Database) Link(source, target ItemRef, relation string) error {
+	if !knownRelations[relation] {
+		return fmt.Errorf("unknown link relation %q", relation)
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf(failedToBeginTxString, err)
+	}
+	defer tx.Rollback()
+
+	source, err = resolveItem(tx, source)
+	if err != nil {
+		return err
+	}
+	target, err = resolveItem(tx, target)
+	if err != nil {
+		return err
+	}
+	if source == target {
+		return fmt.Errorf("cannot link item %s to itself", source)
+	}
+
+	if relation == LinkDependsOn {
+		cycle, err := dependsOn(tx, target, source)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return fmt.Errorf("cannot make %s depend on %s: %w", source, target, ErrDependencyCycle)
+		}
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO links (source_category, source_id, target_category, target_id, relation)
+        VALUES (?, ?, ?, ?, ?)
+        ON CONFLICT DO NOTHING
+    `, source.Category, source.ID, target.Category, target.ID, relation)
+	if err != nil {
+		return fmt.Errorf("failed to link %s to %s: %w", source, target, err)
+	}
+
+	return tx.Commit()
+}
+
+// Unlink removes the relation from the source row to the target row, category names ignore case
+func (db *Database) Unlink(source, target ItemRef, relation string) error {
+	result, err := db.DB.Exec(`
+        DELETE FROM links
+        WHERE source_category = ? COLLATE NOCASE AND source_id = ?
+          AND target_category = ? COLLATE NOCASE AND target_id = ?
+          AND relation = ?
+    `, source.Category, source.ID, target.Category, target.ID, relation)
+	if err != nil {
+		return fmt.Errorf("failed to unlink %s from %s: %w", source, target, err)
+	}
+	return expectAffected(result, fmt.Errorf("no %s link from %s to %s", relation, source, target))
+}
+
+// LinksOf returns the links from or to a row, oldest first. Category names ignore case.
+func (db *Database) LinksOf(item ItemRef) ([]Link, error) {
+	rows, err := db.DB.Query(`
+        SELECT id, created_at, source_category, source_id, target_category, target_id, relation
+        FROM links
+        WHERE (source_category = ? COLLATE NOCASE AND source_id = ?)
+           OR (target_category = ? COLLATE NOCASE AND target_id = ?)
+        ORDER BY id
+    `, item.Category, item.ID, item.Category, item.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query links of %s: %w", item, err)
+	}
+	defer rows.Close()
+
+	var links []Link
+	for rows.Next() {
+		var link Link
+		err := rows.Scan(&link.ID, &link.CreatedAt,
+			&link.Source.Category, &link.Source.ID,
+			&link.Target.Category, &link.Target.ID,
+			&link.Relation)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan link: %w", err)
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return links, nil
+}
+
+// BlockedItems returns the pending items that depend on items which are still pending
+func (db *Database) BlockedItems() ([]BlockedItem, error) {
+	rows, err := db.DB.Query(`
+        SELECT l.source_category, l.source_id, l.target_category, l.target_id
+        FROM links l
+        JOIN pending source
+          ON source.category = l.source_category AND source.item_id = l.source_id
+         AND source.deleted_at IS NULL
+        JOIN pending target
+          ON target.category = l.target_category AND target.item_id = l.target_id
+         AND target.deleted_at IS NULL
+        WHERE l.relation = ?
+        ORDER BY l.source_category, l.source_id, l.target_category, l.target_id
+    `, LinkDependsOn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blocked items: %w", err)
+	}
+	defer rows.Close()
+
+	var blocked []BlockedItem
+	for rows.Next() {
+		var source, target ItemRef
+		if err := rows.Scan(&source.Category, &source.ID, &target.Category, &target.ID); err != nil {
+			return nil, fmt.Errorf("failed to scan blocked item: %w", err)
+		}
+		if n := len(blocked); n > 0 && blocked[n-1].Item == source {
+			blocked[n-1].BlockedBy = append(blocked[n-1].BlockedBy, target)
+			continue
+		}
+		blocked = append(blocked, BlockedItem{Item: source, BlockedBy: []ItemRef{target}})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return blocked, nil
+}
+
+// pendingDependencies returns the items an item depends on that are still pending
+func pendingDependencies(tx *sql.Tx, item ItemRef) ([]ItemRef, error) {
+	rows, err := tx.Query(`
+        SELECT l.target_category, l.target_id
+        FROM links l
+        JOIN pending target
+          ON target.category = l.target_category AND target.item_id = l.target_id
+         AND target.deleted_at IS NULL
+        WHERE l.relation = ? AND l.source_category = ? COLLATE NOCASE AND l.source_id = ?
+        ORDER BY l.target_category, l.target_id
+    `, LinkDependsOn, item.Category, item.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dependencies of %s: %w", item, err)
+	}
+	defer rows.Close()
+
+	var targets []ItemRef
+	for rows.Next() {
+		var target ItemRef
+		if err := rows.Scan(&target.Category, &target.ID); err != nil {
+			return nil, fmt.Errorf("failed to scan dependency: %w", err)
+		}
+		targets = append(targets, target)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return targets, nil
+}
+
+// checkNotBlocked fails with ErrBlocked while an item depends on pending items
+func checkNotBlocked(tx *sql.Tx, item ItemRef) error {
+	targets, err := pendingDependencies(tx, item)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(targets))
+	for i, target := range targets {
+		names[i] = target.String()
+	}
+	return fmt.Errorf("cannot close %s, it waits for %s: %w", item, strings.Join(names, ", "), ErrBlocked)
+}
+
+// removeItemLinks forgets every link from or to a row
+func removeItemLinks(tx *sql.Tx, item ItemRef) error {
+	_, err := tx.Exec(`
+        DELETE FROM links
+        WHERE (source_category = ? COLLATE NOCASE AND source_id = ?)
+           OR (target_category = ? COLLATE NOCASE AND target_id = ?)
+    `, item.Category, item.ID, item.Category, item.ID)
+	if err != nil {
+		return fmt.Errorf("failed to remove links of %s: %w", item, err)
+	}
+	return nil
+}
+
+// removeCategoryLinks forgets every link from or to the rows of a category
+func removeCategoryLinks(tx *sql.Tx, category string) error {
+	_, err := tx.Exec(`DELETE FROM links WHERE source_category = ? COLLATE NOCASE OR target_category = ? COLLATE NOCASE`, category, category)
+	if err != nil {
+		return fmt.Errorf("failed to remove links of %s: %w", category, err)
+	}
+	return nil
+}
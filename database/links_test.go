@@ -0,0 +1,190 @@
+package database
+
+import (
+	"errors"
+	"testing"
+)
+
+// setupLinkedChores creates four open chores to link together
+func setupLinkedChores(t *testing.T, db *TestDB) []ItemRef {
+	t.Helper()
+	setupChoresCategory(t, db)
+
+	var items []ItemRef
+	for _, note := range []string{"Buy paint", "Paint the fence", "Invite friends", "Barbecue"} {
+		id, err := db.CreateRow("Chores", RowData{"Opened": "2024-05-01 09:00", "Note": note})
+		if err != nil {
+			t.Fatalf("CreateRow() error = %v", err)
+		}
+		items = append(items, ItemRef{Category: "Chores", ID: id})
+	}
+	return items
+}
+
+func TestLink(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	items := setupLinkedChores(t, db)
+	paint, fence, friends, barbecue := items[0], items[1], items[2], items[3]
+
+	if err := db.Link(fence, paint, LinkDependsOn); err != nil {
+		t.Fatalf("Link() error = %v", err)
+	}
+	if err := db.Link(barbecue, fence, LinkDependsOn); err != nil {
+		t.Fatalf("Link() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		source    ItemRef
+		target    ItemRef
+		relation  string
+		wantErr   bool
+		wantCycle bool
+	}{
+		{name: "linking twice", source: fence, target: paint, relation: LinkDependsOn},
+		{name: "other relation", source: friends, target: barbecue, relation: LinkBelongsTo},
+		{name: "direct cycle", source: paint, target: fence, relation: LinkDependsOn, wantErr: true, wantCycle: true},
+		{name: "indirect cycle", source: paint, target: barbecue, relation: LinkDependsOn, wantErr: true, wantCycle: true},
+		{name: "cycle of another relation", source: paint, target: barbecue, relation: LinkRelated},
+		{name: "itself", source: paint, target: paint, relation: LinkRelated, wantErr: true},
+		{name: "unknown relation", source: paint, target: friends, relation: "blocks", wantErr: true},
+		{name: "missing row", source: paint, target: ItemRef{Category: "Chores", ID: 99}, relation: LinkRelated, wantErr: true},
+		{name: "missing category", source: paint, target: ItemRef{Category: "Ghost", ID: 1}, relation: LinkRelated, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := db.Link(tt.source, tt.target, tt.relation)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Link() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrDependencyCycle) != tt.wantCycle {
+				t.Errorf("Link() error = %v, want cycle %v", err, tt.wantCycle)
+			}
+		})
+	}
+
+	links, err := db.LinksOf(fence)
+	if err != nil {
+		t.Fatalf("LinksOf() error = %v", err)
+	}
+	if len(links) != 2 || links[0].Target != paint || links[1].Source != barbecue {
+		t.Errorf("LinksOf() = %+v, want the links to paint and from barbecue", links)
+	}
+
+	if err := db.Unlink(fence, paint, LinkDependsOn); err != nil {
+		t.Fatalf("Unlink() error = %v", err)
+	}
+	if err := db.Unlink(fence, paint, LinkDependsOn); err == nil {
+		t.Errorf("Unlink() of a missing link succeeded")
+	}
+	// Without the dependency the former cycle is allowed
+	if err := db.Link(paint, fence, LinkDependsOn); err != nil {
+		t.Errorf("Link() after Unlink() error = %v", err)
+	}
+}
+
+func TestLinkIgnoresCategoryCase(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	items := setupLinkedChores(t, db)
+	paint, fence := items[0], items[1]
+	lowerPaint := ItemRef{Category: "chores", ID: paint.ID}
+	lowerFence := ItemRef{Category: "CHORES", ID: fence.ID}
+
+	if err := db.Link(lowerFence, lowerPaint, LinkDependsOn); err != nil {
+		t.Fatalf("Link() error = %v", err)
+	}
+	if err := db.Link(paint, fence, LinkDependsOn); !errors.Is(err, ErrDependencyCycle) {
+		t.Errorf("Link() of a differently spelled cycle error = %v, want %v", err, ErrDependencyCycle)
+	}
+	if err := db.Link(lowerPaint, paint, LinkRelated); err == nil {
+		t.Errorf("Link() of an item to itself in another spelling succeeded")
+	}
+
+	// Doctor must not take the link for one to a missing category
+	if _, err := db.Doctor(true); err != nil {
+		t.Fatalf("Doctor() error = %v", err)
+	}
+	links, err := db.LinksOf(lowerFence)
+	if err != nil {
+		t.Fatalf("LinksOf() error = %v", err)
+	}
+	if len(links) != 1 || links[0].Source != fence || links[0].Target != paint {
+		t.Errorf("LinksOf() = %+v, want one link stored with the category name", links)
+	}
+
+	if err := db.Unlink(lowerFence, lowerPaint, LinkDependsOn); err != nil {
+		t.Errorf("Unlink() error = %v", err)
+	}
+}
+
+func TestBlockedItems(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	items := setupLinkedChores(t, db)
+	paint, fence, friends, barbecue := items[0], items[1], items[2], items[3]
+
+	links := [][2]ItemRef{{fence, paint}, {barbecue, fence}, {barbecue, friends}}
+	for _, link := range links {
+		if err := db.Link(link[0], link[1], LinkDependsOn); err != nil {
+			t.Fatalf("Link() error = %v", err)
+		}
+	}
+	if _, err := db.CloseItem("Chores", friends.ID, "2024-05-02 10:00:00"); err != nil {
+		t.Fatalf("CloseItem() error = %v", err)
+	}
+
+	blocked, err := db.BlockedItems()
+	if err != nil {
+		t.Fatalf("BlockedItems() error = %v", err)
+	}
+	if len(blocked) != 2 {
+		t.Fatalf("BlockedItems() = %+v, want fence and barbecue", blocked)
+	}
+	if blocked[0].Item != fence || len(blocked[0].BlockedBy) != 1 || blocked[0].BlockedBy[0] != paint {
+		t.Errorf("blocked[0] = %+v, want fence blocked by paint", blocked[0])
+	}
+	if blocked[1].Item != barbecue || len(blocked[1].BlockedBy) != 1 || blocked[1].BlockedBy[0] != fence {
+		t.Errorf("blocked[1] = %+v, want barbecue blocked by fence only", blocked[1])
+	}
+
+	// Deleting the category forgets its links
+	if err := db.DeleteCategory("Chores"); err != nil {
+		t.Fatalf("DeleteCategory() error = %v", err)
+	}
+	var count int
+	if err := db.DB.QueryRow("SELECT COUNT(*) FROM links").Scan(&count); err != nil || count != 0 {
+		t.Errorf("links after DeleteCategory() = %d, %v, want none", count, err)
+	}
+}
+
+func TestCloseItemWaitsForDependencies(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	items := setupLinkedChores(t, db)
+	paint, fence, friends := items[0], items[1], items[2]
+
+	if err := db.Link(fence, paint, LinkDependsOn); err != nil {
+		t.Fatalf("Link() error = %v", err)
+	}
+	if err := db.Link(fence, friends, LinkRelated); err != nil {
+		t.Fatalf("Link() error = %v", err)
+	}
+
+	if _, err := db.CloseItem("Chores", fence.ID, "2024-05-02 10:00:00"); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("CloseItem() of a blocked item error = %v, want %v", err, ErrBlocked)
+	}
+	if pointers := pendingPointers(t, db); !pointers[fence.String()] {
+		t.Errorf("blocked item left pending after a refused close: %v", pointers)
+	}
+
+	// Only dependencies block, and only while they are pending
+	if _, err := db.CloseItem("Chores", paint.ID, "2024-05-02 10:00:00"); err != nil {
+		t.Fatalf("CloseItem() error = %v", err)
+	}
+	if _, err := db.CloseItem("Chores", fence.ID, "2024-05-02 11:00:00"); err != nil {
+		t.Errorf("CloseItem() after its dependency closed error = %v", err)
+	}
+}
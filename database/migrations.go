@@ -0,0 +1,160 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// migration upgrades the schema from the previous version to version
+type migration struct {
+	version     string
+	description string
+	up          func(tx *sql.Tx) error
+}
+
+// migrations lists every schema change after baseVersion, in the order they must be applied.
+// The last entry must match currentVersion.
+var migrations = []migration{
+	{version: "1.1.0", description: "register categories in a categories table", up: migrateCategoriesRegistry},
+	{version: "1.2.0", description: "add per-category datatype overrides", up: createDatatypeOverridesTable},
+	{version: "1.3.0", description: "add a full-text search index", up: createSearchIndex},
+	{version: "1.4.0", description: "add a history of row changes", up: createHistoryTable},
+	{version: "1.5.0", description: "reference pending items by category and id", up: migratePendingReferences},
+	{version: "1.6.0", description: "add links between rows", up: createLinksTable},
+	{version: "1.7.0", description: "check Recurring values as recurrence rules", up: migrateRecurrenceCheck},
+}
+
+// parseVersion splits a "MAJOR.MINOR.PATCH" version into its numeric parts
+func parseVersion(version string) ([3]int, error) {
+	var parsed [3]int
+	parts := strings.Split(strings.TrimSpace(version), ".")
+	if len(parts) != 3 {
+		return parsed, fmt.Errorf("invalid version %q", version)
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return parsed, fmt.Errorf("invalid version %q", version)
+		}
+		parsed[i] = n
+	}
+	return parsed, nil
+}
+
+// compareVersions returns -1, 0 or 1 when a is older, equal or newer than b
+func compareVersions(a, b string) (int, error) {
+	va, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	for i := range va {
+		if va[i] < vb[i] {
+			return -1, nil
+		}
+		if va[i] > vb[i] {
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// createMigrationsTable creates the table recording applied migrations
+func createMigrationsTable(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+        CREATE TABLE IF NOT EXISTS migrations (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            version TEXT NOT NULL UNIQUE,
+            description TEXT NOT NULL,
+            applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+	return nil
+}
+
+// getSchemaVersion reads the schema version stored in the metadata table
+func getSchemaVersion(tx *sql.Tx) (string, error) {
+	var version string
+	err := tx.QueryRow("SELECT version FROM metadata ORDER BY id DESC LIMIT 1").Scan(&version)
+	if err != nil {
+		return "", fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// migrate brings the schema up to currentVersion.
+// All pending migrations run in a single transaction, so a failure leaves the file untouched.
+// Files written by a newer version of the application are refused.
+func (db *Database) migrate() error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf(failedToBeginTxString, err)
+	}
+	defer tx.Rollback()
+
+	version, err := getSchemaVersion(tx)
+	if err != nil {
+		return err
+	}
+
+	cmp, err := compareVersions(version, currentVersion)
+	if err != nil {
+		return err
+	}
+	if cmp > 0 {
+		return fmt.Errorf("database version %s is newer than supported version %s", version, currentVersion)
+	}
+
+	if err := createMigrationsTable(tx); err != nil {
+		return err
+	}
+	if cmp == 0 {
+		return tx.Commit()
+	}
+
+	for _, m := range migrations {
+		cmp, err := compareVersions(m.version, version)
+		if err != nil {
+			return err
+		}
+		if cmp <= 0 {
+			continue
+		}
+
+		db.logger.LogInfo("Applying migration %s: %s", m.version, m.description)
+		if err := m.up(tx); err != nil {
+			return fmt.Errorf("migration %s failed: %w", m.version, err)
+		}
+
+		_, err = tx.Exec("INSERT INTO migrations (version, description) VALUES (?, ?)", m.version, m.description)
+		if err != nil {
+			return fmt.Errorf("failed to record migration %s: %w", m.version, err)
+		}
+		version = m.version
+	}
+
+	_, err = tx.Exec(`
+		UPDATE metadata
+		SET version = ?,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = (SELECT MAX(id) FROM metadata)
+	`, version)
+	if err != nil {
+		return fmt.Errorf("failed to update schema version: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migrations: %w", err)
+	}
+
+	db.logger.LogInfo("Database schema upgraded to version %s", version)
+	return nil
+}
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"Attimo/logging"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		want    int
+		wantErr bool
+	}{
+		{a: "1.0.0", b: "1.0.0", want: 0},
+		{a: "1.0.0", b: "1.1.0", want: -1},
+		{a: "1.10.0", b: "1.9.3", want: 1},
+		{a: "2.0.0", b: "1.99.99", want: 1},
+		{a: "1.0", b: "1.0.0", wantErr: true},
+		{a: "1.x.0", b: "1.0.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			got, err := compareVersions(tt.a, tt.b)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compareVersions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("compareVersions() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// withMigrations temporarily replaces the migration registry
+func withMigrations(t *testing.T, registry []migration) {
+	t.Helper()
+	previous := migrations
+	migrations = registry
+	t.Cleanup(func() { migrations = previous })
+}
+
+// resetToBaseVersion pretends the test database was never migrated
+func resetToBaseVersion(t *testing.T, db *TestDB) {
+	t.Helper()
+	if currentVersion == baseVersion {
+		t.Skip("no migrations registered")
+	}
+	if _, err := db.DB.Exec("UPDATE metadata SET version = ?", baseVersion); err != nil {
+		t.Fatalf("Failed to reset version: %v", err)
+	}
+	if _, err := db.DB.Exec("DELETE FROM migrations"); err != nil {
+		t.Fatalf("Failed to reset migrations: %v", err)
+	}
+}
+
+func TestMigrateRefusesNewerDatabase(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	if _, err := db.DB.Exec("UPDATE metadata SET version = '99.0.0'"); err != nil {
+		t.Fatalf("Failed to bump version: %v", err)
+	}
+
+	if err := db.migrate(); err == nil {
+		t.Errorf("migrate() accepted a database newer than the binary")
+	}
+}
+
+func TestMigrateRecordsMigrations(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	resetToBaseVersion(t, db)
+
+	withMigrations(t, []migration{{
+		version:     currentVersion,
+		description: "create probe",
+		up: func(tx *sql.Tx) error {
+			_, err := tx.Exec("CREATE TABLE migration_probe (id INTEGER)")
+			return err
+		},
+	}})
+
+	if err := db.migrate(); err != nil {
+		t.Fatalf("migrate() error = %v", err)
+	}
+
+	var version string
+	var appliedAt sql.NullTime
+	err := db.DB.QueryRow("SELECT version, applied_at FROM migrations").Scan(&version, &appliedAt)
+	if err != nil {
+		t.Fatalf("Failed to read applied migration: %v", err)
+	}
+	if version != currentVersion || !appliedAt.Valid {
+		t.Errorf("recorded migration = %s at %v, want %s with a timestamp", version, appliedAt, currentVersion)
+	}
+
+	// A second run has nothing left to do
+	if err := db.migrate(); err != nil {
+		t.Errorf("migrate() on an up to date database error = %v", err)
+	}
+}
+
+func TestMigrateRollsBackOnFailure(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	resetToBaseVersion(t, db)
+
+	withMigrations(t, []migration{{
+		version:     currentVersion,
+		description: "fail halfway",
+		up: func(tx *sql.Tx) error {
+			if _, err := tx.Exec("CREATE TABLE migration_probe (id INTEGER)"); err != nil {
+				return err
+			}
+			return fmt.Errorf("boom")
+		},
+	}})
+
+	if err := db.migrate(); err == nil {
+		t.Fatalf("migrate() ignored a failing migration")
+	}
+
+	var version string
+	if err := db.DB.QueryRow("SELECT version FROM metadata").Scan(&version); err != nil {
+		t.Fatalf("Failed to read version: %v", err)
+	}
+	if version != baseVersion {
+		t.Errorf("version after failed migration = %s, want %s", version, baseVersion)
+	}
+
+	var count int
+	if err := db.DB.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'migration_probe'").Scan(&count); err != nil {
+		t.Fatalf("Failed to look up probe table: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("failed migration left its changes behind")
+	}
+}
+
+func TestSetupDatabaseMigratesNewFile(t *testing.T) {
+	logger, err := logging.InitLoggingWithWriter(io.Discard)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "attimo.db")
+	db, err := SetupDatabase(path, logger)
+	if err != nil {
+		t.Fatalf("SetupDatabase() error = %v", err)
+	}
+	db.Close()
+
+	// Reopening an up to date file must not apply anything twice
+	db, err = SetupDatabase(path, logger)
+	if err != nil {
+		t.Fatalf("SetupDatabase() on existing file error = %v", err)
+	}
+	defer db.Close()
+
+	var version string
+	if err := db.DB.QueryRow("SELECT version FROM metadata").Scan(&version); err != nil {
+		t.Fatalf("Failed to read version: %v", err)
+	}
+	if version != currentVersion {
+		t.Errorf("schema version = %s, want %s", version, currentVersion)
+	}
+
+	categories, err := db.GetCategories()
+	if err != nil {
+		t.Fatalf("GetCategories() error = %v", err)
+	}
+	if len(categories) != len(getDefaultCategories()) {
+		t.Errorf("GetCategories() = %v, want the default categories", categories)
+	}
+}
@@ -0,0 +1,199 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// createDatatypeOverridesTable creates the table of category specific datatype settings
+func createDatatypeOverridesTable(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+        CREATE TABLE IF NOT EXISTS datatype_overrides (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+            updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+            category TEXT NOT NULL,
+            datatype TEXT NOT NULL,
+            value_check TEXT,
+            completion_value TEXT,
+            completion_sort TEXT,
+            fill_behavior TEXT,
+            UNIQUE (category, datatype)
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create datatype_overrides table: %w", err)
+	}
+	return nil
+}
+
+// nullIfEmpty stores empty override fields as NULL, meaning "inherit from the datatype"
+func nullIfEmpty(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// applyOverride returns a copy of the datatype with the non-empty override fields applied
+func applyOverride(dt Datatype, override DatatypeOverride) Datatype {
+	if override.ValueCheck != "" {
+		dt.ValueCheck = override.ValueCheck
+	}
+	if override.CompletionValue != "" {
+		dt.CompletionValue = override.CompletionValue
+	}
+	if override.CompletionSort != "" {
+		dt.CompletionSort = override.CompletionSort
+	}
+	if override.FillBehavior != "" {
+		dt.FillBehavior = override.FillBehavior
+	}
+	return dt
+}
+
+// getDatatypeOverride retrieves the override of a datatype in a category, if any
+func getDatatypeOverride(tx *sql.Tx, category, datatype string) (*DatatypeOverride, error) {
+	var valueCheck, completionValue, completionSort, fillBehavior sql.NullString
+	err := tx.QueryRow(`
+		SELECT value_check, completion_value, completion_sort, fill_behavior
+		FROM datatype_overrides
+		WHERE category = ?
+		  AND datatype = ?
+	`, category, datatype).Scan(&valueCheck, &completionValue, &completionSort, &fillBehavior)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get override of %s in %s: %w", datatype, category, err)
+	}
+
+	return &DatatypeOverride{
+		Category:        category,
+		Datatype:        datatype,
+		ValueCheck:      valueCheck.String,
+		CompletionValue: completionValue.String,
+		CompletionSort:  completionSort.String,
+		FillBehavior:    fillBehavior.String,
+	}, nil
+}
+
+// GetCategoryDatatype retrieves the datatype of a column as seen by a category,
+// with the category overrides applied on top of the global datatype
+func GetCategoryDatatype(tx *sql.Tx, category, column string) (*Datatype, error) {
+	dt, err := GetDatatypeByName(tx, column)
+	if err != nil {
+		return nil, err
+	}
+
+	override, err := getDatatypeOverride(tx, category, dt.Name)
+	if err != nil {
+		return nil, err
+	}
+	if override == nil {
+		return dt, nil
+	}
+
+	merged := applyOverride(*dt, *override)
+	return &merged, nil
+}
+
+// SetDatatypeOverride stores the category specific settings of a datatype.
+// Empty fields are inherited from the global datatype.
+func (db *Database) SetDatatypeOverride(override DatatypeOverride) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf(failedToBeginTxString, err)
+	}
+	defer tx.Rollback()
+
+	if err := checkCategoryExists(tx, override.Category); err != nil {
+		return err
+	}
+
+	dt, err := GetDatatypeByName(tx, override.Datatype)
+	if err != nil {
+		return err
+	}
+
+	columns, err := getTableColumns(tx, override.Category)
+	if err != nil {
+		return err
+	}
+	if findColumn(columns, dt.Name) == -1 {
+		return fmt.Errorf("category %s has no column %s", override.Category, dt.Name)
+	}
+
+	if err := validateDatatypeSpec(applyOverride(*dt, override)); err != nil {
+		return fmt.Errorf("invalid override: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO datatype_overrides (category, datatype, value_check, completion_value, completion_sort, fill_behavior)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(category, datatype) DO UPDATE SET
+		value_check = excluded.value_check,
+		completion_value = excluded.completion_value,
+		completion_sort = excluded.completion_sort,
+		fill_behavior = excluded.fill_behavior,
+		updated_at = CURRENT_TIMESTAMP
+	`, override.Category, dt.Name,
+		nullIfEmpty(override.ValueCheck),
+		nullIfEmpty(override.CompletionValue),
+		nullIfEmpty(override.CompletionSort),
+		nullIfEmpty(override.FillBehavior),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store override of %s in %s: %w", dt.Name, override.Category, err)
+	}
+
+	return tx.Commit()
+}
+
+// ClearDatatypeOverride removes the category specific settings of a datatype
+func (db *Database) ClearDatatypeOverride(category, datatype string) error {
+	result, err := db.DB.Exec(`
+		DELETE FROM datatype_overrides
+		WHERE category = ?
+		  AND datatype = ?
+	`, category, datatype)
+	if err != nil {
+		return fmt.Errorf("failed to clear override of %s in %s: %w", datatype, category, err)
+	}
+
+	return expectAffected(result, fmt.Errorf("no override of %s in category %s", datatype, category))
+}
+
+// GetDatatypeOverrides returns the overrides defined for a category
+func (db *Database) GetDatatypeOverrides(category string) ([]DatatypeOverride, error) {
+	rows, err := db.DB.Query(`
+		SELECT datatype, value_check, completion_value, completion_sort, fill_behavior
+		FROM datatype_overrides
+		WHERE category = ?
+		ORDER BY datatype
+	`, category)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query overrides: %w", err)
+	}
+	defer rows.Close()
+
+	var overrides []DatatypeOverride
+	for rows.Next() {
+		var datatype string
+		var valueCheck, completionValue, completionSort, fillBehavior sql.NullString
+		if err := rows.Scan(&datatype, &valueCheck, &completionValue, &completionSort, &fillBehavior); err != nil {
+			return nil, fmt.Errorf("failed to scan override: %w", err)
+		}
+		overrides = append(overrides, DatatypeOverride{
+			Category:        category,
+			Datatype:        datatype,
+			ValueCheck:      valueCheck.String,
+			CompletionValue: completionValue.String,
+			CompletionSort:  completionSort.String,
+			FillBehavior:    fillBehavior.String,
+		})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return overrides, nil
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestDatatypeOverrides(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	rating := Datatype{Name: "Rating", VariableType: IntType, CompletionValue: NoCompletion, CompletionSort: NoSort, ValueCheck: RangeCheck + "(1,10)", FillBehavior: Open}
+	ratingID, err := db.CreateDatatype(rating)
+	if err != nil {
+		t.Fatalf("Failed to create test datatype: %v", err)
+	}
+	for _, name := range []string{"Movies", "Restaurants"} {
+		if err := db.CreateCategory(CategoryTemplate{Name: name, ColumnsID: []int{1, ratingID}}); err != nil {
+			t.Fatalf("Failed to create test category: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		override DatatypeOverride
+		wantErr  bool
+	}{
+		{
+			name:     "valid override",
+			override: DatatypeOverride{Category: "Movies", Datatype: "Rating", ValueCheck: RangeCheck + "(1,5)", FillBehavior: Close},
+			wantErr:  false,
+		},
+		{
+			name:     "invalid check",
+			override: DatatypeOverride{Category: "Movies", Datatype: "Rating", ValueCheck: RangeCheck + "(5,1)"},
+			wantErr:  true,
+		},
+		{
+			name:     "column not in category",
+			override: DatatypeOverride{Category: "General", Datatype: "Rating", ValueCheck: NoCheck},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown category",
+			override: DatatypeOverride{Category: "Books", Datatype: "Rating", ValueCheck: NoCheck},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := db.SetDatatypeOverride(tt.override)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SetDatatypeOverride() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	movies, err := GetCategoryDatatype(tx, "Movies", "Rating")
+	if err != nil {
+		t.Fatalf("GetCategoryDatatype() error = %v", err)
+	}
+	restaurants, err := GetCategoryDatatype(tx, "Restaurants", "Rating")
+	if err != nil {
+		t.Fatalf("GetCategoryDatatype() error = %v", err)
+	}
+	tx.Rollback()
+
+	if movies.ValueCheck != RangeCheck+"(1,5)" || movies.FillBehavior != Close || movies.VariableType != IntType {
+		t.Errorf("GetCategoryDatatype(Movies) = %+v, want overridden check and fill behavior", movies)
+	}
+	if restaurants.ValueCheck != rating.ValueCheck || restaurants.FillBehavior != Open {
+		t.Errorf("GetCategoryDatatype(Restaurants) = %+v, want the global datatype", restaurants)
+	}
+
+	if _, err := db.CreateRow("Movies", RowData{"Note": "Alien", "Rating": 8}); err == nil {
+		t.Errorf("CreateRow() ignored the Movies override")
+	}
+	if _, err := db.CreateRow("Restaurants", RowData{"Note": "Da Marco", "Rating": 8}); err != nil {
+		t.Errorf("CreateRow() error = %v", err)
+	}
+
+	if err := db.ClearDatatypeOverride("Movies", "Rating"); err != nil {
+		t.Fatalf("ClearDatatypeOverride() error = %v", err)
+	}
+	if _, err := db.CreateRow("Movies", RowData{"Note": "Alien", "Rating": 8}); err != nil {
+		t.Errorf("CreateRow() after clearing override error = %v", err)
+	}
+}
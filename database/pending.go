@@ -3,9 +3,14 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
-// createPendingTable creates the pending tracking table
+// createPendingTable creates the pending tracking table as it was at the base schema version.
+// migratePendingReferences upgrades it to separate category and item columns.
 func createPendingTable(tx *sql.Tx) error {
 	_, err := tx.Exec(`
         CREATE TABLE IF NOT EXISTS pending (
@@ -30,17 +35,92 @@ func createPendingTable(tx *sql.Tx) error {
 	return err
 }
 
+// migratePendingReferences replaces the "Category:ID" pointer column with
+// category and item_id columns. It fails on pointers without a numeric id,
+// naming them, rather than forgetting those pending items.
+func migratePendingReferences(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+        CREATE TABLE pending_references (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+            updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+            deleted_at DATETIME DEFAULT NULL,
+            category TEXT NOT NULL,
+            item_id INTEGER NOT NULL,
+            UNIQUE (category, item_id)
+        )
+    `)
+	if err != nil {
+		return fmt.Errorf("failed to create pending references table: %w", err)
+	}
+
+	rows, err := tx.Query(`SELECT id, created_at, updated_at, deleted_at, pointer FROM pending ORDER BY id`)
+	if err != nil {
+		return fmt.Errorf("failed to query pending pointers: %w", err)
+	}
+
+	type reference struct {
+		id                   int
+		createdAt, updatedAt interface{}
+		deletedAt            interface{}
+		category             string
+		itemID               int
+	}
+	var references []reference
+	var invalid []string
+	for rows.Next() {
+		var ref reference
+		var pointer string
+		if err := rows.Scan(&ref.id, &ref.createdAt, &ref.updatedAt, &ref.deletedAt, &pointer); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan pending row: %w", err)
+		}
+		ref.category, ref.itemID, err = parsePointer(pointer)
+		if err != nil {
+			invalid = append(invalid, strconv.Quote(pointer))
+			continue
+		}
+		references = append(references, ref)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid pending pointers %s, fix or delete them in the pending table", strings.Join(invalid, ", "))
+	}
+
+	for _, ref := range references {
+		_, err := tx.Exec(`
+            INSERT INTO pending_references (id, created_at, updated_at, deleted_at, category, item_id)
+            VALUES (?, ?, ?, ?, ?, ?)
+        `, ref.id, storedValue(ref.createdAt), storedValue(ref.updatedAt), storedValue(ref.deletedAt), ref.category, ref.itemID)
+		if err != nil {
+			return fmt.Errorf("failed to migrate pending item %s:%d: %w", ref.category, ref.itemID, err)
+		}
+	}
+
+	statements := []string{
+		"DROP TABLE pending",
+		"ALTER TABLE pending_references RENAME TO pending",
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("failed to replace pending table: %w", err)
+		}
+	}
+	return nil
+}
+
 // addToPending adds a new item to the pending tracking
 func (db *Database) addToPending(tx *sql.Tx, category string, itemID int) error {
-	pointer := fmt.Sprintf("%s:%d", category, itemID)
-
 	_, err := tx.Exec(`
-        INSERT INTO pending (pointer) 
-        VALUES (?) 
-        ON CONFLICT(pointer) DO UPDATE SET
+        INSERT INTO pending (category, item_id) 
+        VALUES (?, ?) 
+        ON CONFLICT(category, item_id) DO UPDATE SET
         updated_at = CURRENT_TIMESTAMP,
         deleted_at = NULL
-    `, pointer)
+    `, category, itemID)
 
 	if err != nil {
 		return fmt.Errorf("failed to add to pending: %w", err)
@@ -50,15 +130,13 @@ func (db *Database) addToPending(tx *sql.Tx, category string, itemID int) error
 
 // removeFromPending marks an item as no longer pending
 func (db *Database) removeFromPending(tx *sql.Tx, category string, itemID int) error {
-	pointer := fmt.Sprintf("%s:%d", category, itemID)
-
 	_, err := tx.Exec(`
         UPDATE pending 
         SET deleted_at = CURRENT_TIMESTAMP,
             updated_at = CURRENT_TIMESTAMP
-        WHERE pointer = ? 
+        WHERE category = ? AND item_id = ?
         AND deleted_at IS NULL
-    `, pointer)
+    `, category, itemID)
 
 	if err != nil {
 		return fmt.Errorf("failed to remove from pending: %w", err)
@@ -66,64 +144,216 @@ func (db *Database) removeFromPending(tx *sql.Tx, category string, itemID int) e
 	return nil
 }
 
-// GetPendingItems returns all currently pending items with their details
+// deleteFromPending forgets an item entirely, whether it is pending or was closed
+func (db *Database) deleteFromPending(tx *sql.Tx, category string, itemID int) error {
+	if _, err := tx.Exec(`DELETE FROM pending WHERE category = ? AND item_id = ?`, category, itemID); err != nil {
+		return fmt.Errorf("failed to remove from pending: %w", err)
+	}
+	return nil
+}
+
+// removeCategoryFromPending forgets every pending item of a category
+func (db *Database) removeCategoryFromPending(tx *sql.Tx, category string) error {
+	_, err := tx.Exec(`
+        DELETE FROM pending
+        WHERE category = ?
+    `, category)
+
+	if err != nil {
+		return fmt.Errorf("failed to remove pending items of %s: %w", category, err)
+	}
+	return nil
+}
+
+// GetPendingPointers returns the "Category:ID" pointers of all pending items, newest first
 func (db *Database) GetPendingPointers() ([]string, error) {
-	// Get all pending items
+	references, err := db.pendingReferences()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]string, len(references))
+	for i, ref := range references {
+		results[i] = fmt.Sprintf("%s:%d", ref.Category, ref.ID)
+	}
+	return results, nil
+}
+
+// pendingReferences returns the category and id of all pending items, newest first
+func (db *Database) pendingReferences() ([]PendingItem, error) {
 	rows, err := db.DB.Query(`
-        SELECT pointer
+        SELECT category, item_id
         FROM pending
         WHERE deleted_at IS NULL
-        ORDER BY created_at DESC
+        ORDER BY created_at DESC, id DESC
     `)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query pending pointers: %w", err)
+		return nil, fmt.Errorf("failed to query pending items: %w", err)
 	}
 	defer rows.Close()
 
-	var results []string
+	var results []PendingItem
 	for rows.Next() {
-		var pointer string
-
-		if err := rows.Scan(&pointer); err != nil {
+		var ref PendingItem
+		if err := rows.Scan(&ref.Category, &ref.ID); err != nil {
 			return nil, fmt.Errorf("failed to scan pending row: %w", err)
 		}
-		results = append(results, pointer)
+		results = append(results, ref)
 	}
+	return results, rows.Err()
+}
 
-	return results, nil
+// parsePointer splits a legacy "Category:ID" pointer at its last colon
+func parsePointer(pointer string) (string, int, error) {
+	sep := strings.LastIndex(pointer, ":")
+	if sep <= 0 {
+		return "", 0, fmt.Errorf("invalid pending pointer %q", pointer)
+	}
+	id, err := strconv.Atoi(pointer[sep+1:])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid pending pointer %q: %w", pointer, err)
+	}
+	return pointer[:sep], id, nil
 }
 
-// Helper function to convert a row into a map
-func (db *Database) queryRowToMap(query string, args ...interface{}) (RowData, error) {
-	rows, err := db.DB.Query(query, args...)
+// GetPendingItems returns all currently pending items with their details, oldest first.
+// Items whose row no longer exists are skipped.
+func (db *Database) GetPendingItems() ([]PendingItem, error) {
+	references, err := db.pendingReferences()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf(failedToBeginTxString, err)
+	}
+	defer tx.Rollback()
+
+	// One query per category rather than per item
+	var categories []string
+	idsByCategory := make(map[string][]int)
+	for _, ref := range references {
+		if _, ok := idsByCategory[ref.Category]; !ok {
+			categories = append(categories, ref.Category)
+		}
+		idsByCategory[ref.Category] = append(idsByCategory[ref.Category], ref.ID)
+	}
+
+	now := time.Now()
+	items := make([]PendingItem, 0, len(references))
+	for _, category := range categories {
+		// A category dropped behind the app's back must not hide the other pending items
+		exists, err := tableExists(tx, category)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			db.logger.LogWarn("Skipping %d pending items of missing category %s", len(idsByCategory[category]), category)
+			continue
+		}
+
+		rows, err := db.readRows(tx, category, idsByCategory[category])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pending items of %s: %w", category, err)
+		}
+		for _, row := range rows {
+			id, _ := row["id"].(int)
+			item := PendingItem{Category: category, ID: id, Preview: pendingPreview(row)}
+			if opened, ok := row["Opened"].(time.Time); ok {
+				item.Opened = opened
+				item.Elapsed = now.Sub(opened)
+			}
+			items = append(items, item)
+		}
+	}
+
+	// Items without an opening time go last
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].Opened, items[j].Opened
+		if a.IsZero() != b.IsZero() {
+			return b.IsZero()
+		}
+		return a.Before(b)
+	})
+	return items, nil
+}
+
+// readRows returns the decoded rows of a category with the given ids, skipping deleted and missing ones
+func (db *Database) readRows(tx *sql.Tx, categoryName string, ids []int) ([]RowData, error) {
+	table, err := quoteCategory(categoryName)
+	if err != nil {
+		return nil, err
+	}
+	types, err := getColumnTypes(tx, categoryName)
 	if err != nil {
 		return nil, err
 	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	query := fmt.Sprintf(
+		"SELECT * FROM %s WHERE deleted_at IS NULL AND id IN (%s)",
+		table, strings.Join(placeholders, ", "),
+	)
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rows: %w", err)
+	}
 	defer rows.Close()
 
 	columns, err := rows.Columns()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf(columnsFetchErrorString, err)
 	}
 
-	if !rows.Next() {
-		return nil, sql.ErrNoRows
+	var results []RowData
+	for rows.Next() {
+		row, err := scanRow(rows, columns)
+		if err != nil {
+			return nil, err
+		}
+		db.decodeRow(row, types)
+		results = append(results, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return results, nil
+}
+
+// pendingPreview joins the Note and Project values of a row
+func pendingPreview(row RowData) string {
+	var parts []string
+	for _, column := range []string{"Note", "Project"} {
+		if value, ok := row[column].(string); ok && strings.TrimSpace(value) != "" {
+			parts = append(parts, strings.TrimSpace(value))
+		}
 	}
+	return strings.Join(parts, " · ")
+}
 
-	values := make([]interface{}, len(columns))
-	valuePointers := make([]interface{}, len(columns))
-	for i := range values {
-		valuePointers[i] = &values[i]
+// Helper function to convert a row into a map
+func (db *Database) queryRowToMap(query string, args ...interface{}) (RowData, error) {
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	if err := rows.Scan(valuePointers...); err != nil {
+	columns, err := rows.Columns()
+	if err != nil {
 		return nil, err
 	}
 
-	result := make(RowData)
-	for i, col := range columns {
-		result[col] = values[i]
+	if !rows.Next() {
+		return nil, sql.ErrNoRows
 	}
 
-	return result, nil
+	return scanRow(rows, columns)
 }
@@ -0,0 +1,177 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestParsePointer(t *testing.T) {
+	tests := []struct {
+		name     string
+		pointer  string
+		category string
+		id       int
+		wantErr  bool
+	}{
+		{name: "simple", pointer: "General:12", category: "General", id: 12},
+		{name: "colon in category", pointer: "Work:Home:3", category: "Work:Home", id: 3},
+		{name: "missing separator", pointer: "General", wantErr: true},
+		{name: "missing category", pointer: ":4", wantErr: true},
+		{name: "invalid id", pointer: "General:x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			category, id, err := parsePointer(tt.pointer)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePointer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (category != tt.category || id != tt.id) {
+				t.Errorf("parsePointer() = %q, %d, want %q, %d", category, id, tt.category, tt.id)
+			}
+		})
+	}
+}
+
+func TestGetPendingItems(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	setupChoresCategory(t, db)
+
+	rows := []RowData{
+		{"Opened": "2024-05-03 09:00", "Note": "Water plants"},
+		{"Opened": "2024-05-01 09:00", "Note": "Fix the bike"},
+		{"Opened": "2024-05-02 09:00", "Note": "Call plumber"},
+	}
+	for _, row := range rows {
+		if _, err := db.CreateRow("Chores", row); err != nil {
+			t.Fatalf("CreateRow() error = %v", err)
+		}
+	}
+	if _, err := db.CloseItem("Chores", 3, "2024-05-04 10:00:00"); err != nil {
+		t.Fatalf("CloseItem() error = %v", err)
+	}
+
+	// A pending item of another category without an opening time, one whose row is gone
+	// and one whose category table was dropped
+	generalID, err := db.CreateRow("General", RowData{"Note": "Read", "Project": "Books"})
+	if err != nil {
+		t.Fatalf("CreateRow() error = %v", err)
+	}
+	_, err = db.DB.Exec("INSERT INTO pending (category, item_id) VALUES ('General', ?), ('Chores', 99), ('Dropped', 1)", generalID)
+	if err != nil {
+		t.Fatalf("failed to insert pending items: %v", err)
+	}
+
+	items, err := db.GetPendingItems()
+	if err != nil {
+		t.Fatalf("GetPendingItems() error = %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("GetPendingItems() returned %d items, want 3", len(items))
+	}
+
+	// Oldest first, items without an opening time last
+	want := []struct {
+		category string
+		id       int
+		preview  string
+		opened   bool
+	}{
+		{category: "Chores", id: 2, preview: "Fix the bike", opened: true},
+		{category: "Chores", id: 1, preview: "Water plants", opened: true},
+		{category: "General", id: generalID, preview: "Read · Books"},
+	}
+	for i, w := range want {
+		item := items[i]
+		if item.Category != w.category || item.ID != w.id || item.Preview != w.preview {
+			t.Errorf("item %d = %+v, want %s:%d %q", i, item, w.category, w.id, w.preview)
+		}
+		if w.opened && (item.Opened.IsZero() || item.Elapsed <= 0) {
+			t.Errorf("item %d has no opening time: %+v", i, item)
+		}
+	}
+}
+
+// legacyPendingTable recreates the pointer based pending table of older databases, holding pointers
+func legacyPendingTable(t *testing.T, db *TestDB, pointers string) *sql.Tx {
+	t.Helper()
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if _, err := tx.Exec("DROP TABLE pending"); err != nil {
+		t.Fatalf("failed to drop pending table: %v", err)
+	}
+	if err := createPendingTable(tx); err != nil {
+		t.Fatalf("createPendingTable() error = %v", err)
+	}
+	if _, err := tx.Exec("INSERT INTO pending (pointer, deleted_at) VALUES " + pointers); err != nil {
+		t.Fatalf("failed to insert pointers: %v", err)
+	}
+	return tx
+}
+
+func TestMigratePendingReferences(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	tx := legacyPendingTable(t, db, `
+        ('General:1', NULL),
+        ('Work:Home:3', NULL),
+        ('General:2', '2024-05-01 10:00:00')
+    `)
+	if err := migratePendingReferences(tx); err != nil {
+		t.Fatalf("migratePendingReferences() error = %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	got := pendingPointers(t, db)
+	want := map[string]bool{"General:1": true, "Work:Home:3": true}
+	if len(got) != len(want) {
+		t.Fatalf("pending pointers = %v, want %v", got, want)
+	}
+	for pointer := range want {
+		if !got[pointer] {
+			t.Errorf("pending pointer %s missing after migration, got %v", pointer, got)
+		}
+	}
+
+	// The closed item keeps its row, so reopening it revives the same entry
+	var deletedAt sql.NullString
+	err := db.DB.QueryRow("SELECT deleted_at FROM pending WHERE category = 'General' AND item_id = 2").Scan(&deletedAt)
+	if err != nil || !deletedAt.Valid {
+		t.Errorf("closed item after migration: deleted_at = %v, %v", deletedAt, err)
+	}
+
+	// Category and id are unique together
+	if _, err := db.DB.Exec("INSERT INTO pending (category, item_id) VALUES ('General', 1)"); err == nil {
+		t.Errorf("duplicate pending reference was accepted")
+	}
+}
+
+func TestMigratePendingReferencesRejectsMalformedPointers(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	tx := legacyPendingTable(t, db, `
+        ('General:1', NULL),
+        ('General:x', NULL),
+        (':3', '2024-05-01 10:00:00')
+    `)
+	defer tx.Rollback()
+
+	err := migratePendingReferences(tx)
+	if err == nil {
+		t.Fatalf("migratePendingReferences() accepted malformed pointers")
+	}
+	for _, pointer := range []string{`"General:x"`, `":3"`} {
+		if !strings.Contains(err.Error(), pointer) {
+			t.Errorf("migratePendingReferences() error = %v, want it to name %s", err, pointer)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Filter operators
+const (
+	OpEq      = "="
+	OpNe      = "!="
+	OpLt      = "<"
+	OpLe      = "<="
+	OpGt      = ">"
+	OpGe      = ">="
+	OpBetween = "between"     // Values holds the lower and upper bound, both included
+	OpLike    = "like"        // Value is a SQL LIKE pattern, e.g. "%report%"
+	OpIn      = "in"          // Values holds the accepted values
+	OpIsNull  = "is null"     // matches missing and empty values
+	OpNotNull = "is not null" // matches values that are set and not empty
+)
+
+// Filter is a single condition on a column of a category
+type Filter struct {
+	Column string
+	Op     string        // one of the Op constants, "=" when empty
+	Value  interface{}   // compared value, for the single-value operators
+	Values []interface{} // compared values, for between and in
+}
+
+// SortKey orders rows by a column
+type SortKey struct {
+	Column string
+	Desc   bool
+}
+
+// Query selects and orders the rows of a category.
+// The row matches when every Where filter matches and, for each AnyOf group,
+// at least one of the group's filters matches.
+type Query struct {
+	Where []Filter
+	AnyOf [][]Filter
+	Sort  []SortKey // rows are ordered by id, newest first, after these keys
+}
+
+// DateRange filters a time column on [from, to]. A zero bound leaves that side open.
+func DateRange(column string, from, to time.Time) Filter {
+	switch {
+	case from.IsZero():
+		return Filter{Column: column, Op: OpLe, Value: to}
+	case to.IsZero():
+		return Filter{Column: column, Op: OpGe, Value: from}
+	default:
+		return Filter{Column: column, Op: OpBetween, Values: []interface{}{from, to}}
+	}
+}
+
+// EqualFilters turns column/value pairs into equality filters, in column order.
+// A nil value matches rows where the column is not set.
+func EqualFilters(values RowData) []Filter {
+	columns := make([]string, 0, len(values))
+	for col := range values {
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+
+	filters := make([]Filter, 0, len(columns))
+	for _, col := range columns {
+		if values[col] == nil {
+			filters = append(filters, Filter{Column: col, Op: OpIsNull})
+			continue
+		}
+		filters = append(filters, Filter{Column: col, Op: OpEq, Value: values[col]})
+	}
+	return filters
+}
+
+// queryBuilder translates a Query into a parameterised WHERE and ORDER BY clause
+type queryBuilder struct {
+	columns map[string]bool   // columns that may be referenced
+	types   map[string]string // variable type of each column
+	args    []interface{}
+}
+
+// newQueryBuilder reads the columns of a category. Only its datatype columns,
+// id, created_at and updated_at may be filtered or sorted on.
+func (db *Database) newQueryBuilder(categoryName string) (*queryBuilder, error) {
+	columns, err := db.GetCategoryColumns(categoryName)
+	if err != nil {
+		return nil, fmt.Errorf(columnsFetchErrorString, err)
+	}
+	types, err := getColumnTypes(db.DB, categoryName)
+	if err != nil {
+		return nil, err
+	}
+
+	valid := map[string]bool{"id": true, "created_at": true, "updated_at": true}
+	for _, col := range columns {
+		valid[col] = true
+	}
+	return &queryBuilder{columns: valid, types: types}, nil
+}
+
+// where returns the conditions of the query, always excluding deleted rows
+func (b *queryBuilder) where(q Query) (string, error) {
+	conditions := []string{"deleted_at IS NULL"}
+
+	for _, f := range q.Where {
+		cond, err := b.condition(f)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, cond)
+	}
+
+	for _, group := range q.AnyOf {
+		if len(group) == 0 {
+			continue
+		}
+		alternatives := make([]string, 0, len(group))
+		for _, f := range group {
+			cond, err := b.condition(f)
+			if err != nil {
+				return "", err
+			}
+			alternatives = append(alternatives, cond)
+		}
+		conditions = append(conditions, "("+strings.Join(alternatives, " OR ")+")")
+	}
+
+	return strings.Join(conditions, " AND "), nil
+}
+
+// condition translates a single filter, appending its values to the arguments
+func (b *queryBuilder) condition(f Filter) (string, error) {
+	if !b.columns[f.Column] {
+		return "", fmt.Errorf("invalid filter column: %s", f.Column)
+	}
+	col := quoteIdentifier(f.Column)
+
+	op := strings.ToLower(strings.TrimSpace(f.Op))
+	if op == "" {
+		op = OpEq
+	}
+
+	switch op {
+	case OpEq, OpNe, OpLt, OpLe, OpGt, OpGe:
+		if f.Value == nil {
+			return "", fmt.Errorf("filter on %s: operator %s needs a value, use %q or %q for missing values", f.Column, op, OpIsNull, OpNotNull)
+		}
+		arg, err := b.value(f.Column, f.Value)
+		if err != nil {
+			return "", err
+		}
+		b.args = append(b.args, arg)
+		return fmt.Sprintf("%s %s ?", col, op), nil
+	case OpBetween:
+		if len(f.Values) != 2 {
+			return "", fmt.Errorf("filter on %s: %s needs exactly two values", f.Column, OpBetween)
+		}
+		for _, v := range f.Values {
+			arg, err := b.value(f.Column, v)
+			if err != nil {
+				return "", err
+			}
+			b.args = append(b.args, arg)
+		}
+		return fmt.Sprintf("%s BETWEEN ? AND ?", col), nil
+	case OpIn:
+		if len(f.Values) == 0 {
+			return "", fmt.Errorf("filter on %s: %s needs at least one value", f.Column, OpIn)
+		}
+		placeholders := make([]string, len(f.Values))
+		for i, v := range f.Values {
+			arg, err := b.value(f.Column, v)
+			if err != nil {
+				return "", err
+			}
+			b.args = append(b.args, arg)
+			placeholders[i] = "?"
+		}
+		return fmt.Sprintf("%s IN (%s)", col, strings.Join(placeholders, ", ")), nil
+	case OpLike:
+		pattern, ok := f.Value.(string)
+		if !ok {
+			return "", fmt.Errorf("filter on %s: %s needs a text pattern", f.Column, OpLike)
+		}
+		b.args = append(b.args, pattern)
+		return fmt.Sprintf("%s LIKE ?", col), nil
+	case OpIsNull:
+		return fmt.Sprintf("(%s IS NULL OR %s = '')", col, col), nil
+	case OpNotNull:
+		return fmt.Sprintf("(%s IS NOT NULL AND %s != '')", col, col), nil
+	default:
+		return "", fmt.Errorf("filter on %s: unknown operator %q", f.Column, f.Op)
+	}
+}
+
+// value coerces a filter value to the column's datatype and encodes it for comparison
+func (b *queryBuilder) value(column string, value interface{}) (interface{}, error) {
+	variableType := b.types[column]
+	coerced, err := CoerceValue(variableType, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter value for %s: %w", column, err)
+	}
+	stored, err := encodeValue(variableType, coerced)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter value for %s: %w", column, err)
+	}
+	return stored, nil
+}
+
+// sortKeys checks the sort keys of a query and returns them with id as the final tie breaker,
+// so that every row has a unique position
+func (b *queryBuilder) sortKeys(keys []SortKey) ([]SortKey, error) {
+	result := make([]SortKey, 0, len(keys)+1)
+	for _, key := range keys {
+		if !b.columns[key.Column] {
+			return nil, fmt.Errorf("invalid sort column: %s", key.Column)
+		}
+		result = append(result, key)
+		if key.Column == "id" {
+			return result, nil // id is unique, later keys cannot change the order
+		}
+	}
+	return append(result, SortKey{Column: "id", Desc: true}), nil
+}
+
+// orderBy returns the ORDER BY clause for the sort keys returned by sortKeys
+func orderBy(keys []SortKey) string {
+	terms := make([]string, len(keys))
+	for i, key := range keys {
+		direction := "ASC"
+		if key.Desc {
+			direction = "DESC"
+		}
+		terms[i] = quoteIdentifier(key.Column) + " " + direction
+	}
+	return strings.Join(terms, ", ")
+}
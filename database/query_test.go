@@ -0,0 +1,99 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+// setupQueryTestCategory creates a Tasks category with a date and a number column and fills it
+func setupQueryTestCategory(t *testing.T, db *TestDB) {
+	t.Helper()
+
+	datatypes := []Datatype{
+		{Name: "Due", VariableType: TimeType, CompletionValue: DateCompletion, CompletionSort: NoSort, ValueCheck: NoCheck, FillBehavior: Open},
+		{Name: "Effort", VariableType: IntType, CompletionValue: NoCompletion, CompletionSort: NoSort, ValueCheck: NoCheck, FillBehavior: Open},
+	}
+	createTestCategory(t, db, "Tasks", datatypes, 1, 2) // Note, Project
+
+	rows := []RowData{
+		{"Note": "Write report", "Project": "A", "Due": "2024-03-01", "Effort": 3},
+		{"Note": "Review report", "Project": "A", "Due": "2024-03-15", "Effort": 1},
+		{"Note": "Plan trip", "Project": "B", "Due": "2024-04-02", "Effort": 5},
+		{"Note": "Call bank", "Project": "C", "Effort": 2},
+	}
+	for _, row := range rows {
+		if _, err := db.CreateRow("Tasks", row); err != nil {
+			t.Fatalf("Failed to create test row: %v", err)
+		}
+	}
+}
+
+func TestListRowsQuery(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	setupQueryTestCategory(t, db)
+
+	march := DateRange("Due", time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local), time.Date(2024, 3, 31, 0, 0, 0, 0, time.Local))
+
+	tests := []struct {
+		name    string
+		query   Query
+		wantIDs []int
+		wantErr bool
+	}{
+		{name: "default order", query: Query{}, wantIDs: []int{4, 3, 2, 1}},
+		{name: "greater than", query: Query{Where: []Filter{{Column: "Effort", Op: OpGt, Value: 2}}}, wantIDs: []int{3, 1}},
+		{name: "text value is coerced", query: Query{Where: []Filter{{Column: "Effort", Op: OpLe, Value: "2"}}}, wantIDs: []int{4, 2}},
+		{name: "between", query: Query{Where: []Filter{{Column: "Effort", Op: OpBetween, Values: []interface{}{2, 3}}}}, wantIDs: []int{4, 1}},
+		{name: "date range", query: Query{Where: []Filter{march}}, wantIDs: []int{2, 1}},
+		{name: "open date range", query: Query{Where: []Filter{DateRange("Due", time.Date(2024, 3, 10, 0, 0, 0, 0, time.Local), time.Time{})}}, wantIDs: []int{3, 2}},
+		{name: "like", query: Query{Where: []Filter{{Column: "Note", Op: OpLike, Value: "%report"}}}, wantIDs: []int{2, 1}},
+		{name: "in", query: Query{Where: []Filter{{Column: "Project", Op: OpIn, Values: []interface{}{"B", "C"}}}}, wantIDs: []int{4, 3}},
+		{name: "is null", query: Query{Where: []Filter{{Column: "Due", Op: OpIsNull}}}, wantIDs: []int{4}},
+		{name: "not null", query: Query{Where: []Filter{{Column: "Due", Op: OpNotNull}}}, wantIDs: []int{3, 2, 1}},
+		{
+			name: "or group",
+			query: Query{
+				Where: []Filter{{Column: "Effort", Op: OpGe, Value: 2}},
+				AnyOf: [][]Filter{{{Column: "Project", Value: "A"}, {Column: "Project", Value: "C"}}},
+			},
+			wantIDs: []int{4, 1},
+		},
+		{
+			name:    "multi column sort",
+			query:   Query{Sort: []SortKey{{Column: "Project"}, {Column: "Effort", Desc: true}}},
+			wantIDs: []int{1, 2, 3, 4},
+		},
+		{name: "unknown operator", query: Query{Where: []Filter{{Column: "Effort", Op: "~", Value: 1}}}, wantErr: true},
+		{name: "unknown column", query: Query{Where: []Filter{{Column: "Effort = 1 OR 1", Value: 1}}}, wantErr: true},
+		{name: "deleted_at is not filterable", query: Query{Where: []Filter{{Column: "deleted_at", Op: OpNotNull}}}, wantErr: true},
+		{name: "unknown sort column", query: Query{Sort: []SortKey{{Column: "Effort DESC; --"}}}, wantErr: true},
+		{name: "between needs two values", query: Query{Where: []Filter{{Column: "Effort", Op: OpBetween, Values: []interface{}{1}}}}, wantErr: true},
+		{name: "invalid value", query: Query{Where: []Filter{{Column: "Effort", Op: OpLt, Value: "many"}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, total, err := db.ListRowsQuery("Tasks", tt.query, 1, 10)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ListRowsQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			ids := make([]int, len(rows))
+			for i, row := range rows {
+				ids[i] = row["id"].(int)
+			}
+			if total != len(tt.wantIDs) || len(ids) != len(tt.wantIDs) {
+				t.Fatalf("ListRowsQuery() ids = %v (total %d), want %v", ids, total, tt.wantIDs)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantIDs[i] {
+					t.Fatalf("ListRowsQuery() ids = %v, want %v", ids, tt.wantIDs)
+				}
+			}
+		})
+	}
+}
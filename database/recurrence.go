@@ -0,0 +1,284 @@
+package database
+
+import (
+	"Attimo/timeparse"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence units
+const (
+	recurDay   = "day"
+	recurWeek  = "week"
+	recurMonth = "month"
+	recurYear  = "year"
+)
+
+// recurrenceShorthands maps the values offered by the Recurring completion to their unit
+var recurrenceShorthands = map[string]string{
+	"daily":   recurDay,
+	"weekly":  recurWeek,
+	"monthly": recurMonth,
+	"yearly":  recurYear,
+}
+
+// recurrence is a parsed Recurring value, e.g. "Weekly" or "every 2 weeks on Mon,Thu"
+type recurrence struct {
+	unit     string
+	interval int
+	weekdays map[time.Weekday]bool // only for weekly rules, empty means the same weekday
+}
+
+// parseRecurrence parses "Daily", "Weekly", "Monthly", "Yearly" and
+// "every [N] day(s)|week(s)|month(s)|year(s)", optionally followed by "on Mon,Thu" for weeks
+func parseRecurrence(rule string) (recurrence, error) {
+	text := strings.ToLower(strings.TrimSpace(rule))
+	if unit, ok := recurrenceShorthands[text]; ok {
+		return recurrence{unit: unit, interval: 1}, nil
+	}
+
+	fields := strings.Fields(text)
+	if len(fields) < 2 || fields[0] != "every" {
+		return recurrence{}, fmt.Errorf("invalid recurrence %q", rule)
+	}
+	fields = fields[1:]
+
+	r := recurrence{interval: 1}
+	if n, err := strconv.Atoi(fields[0]); err == nil {
+		if n < 1 {
+			return recurrence{}, fmt.Errorf("invalid recurrence %q: interval must be positive", rule)
+		}
+		r.interval = n
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return recurrence{}, fmt.Errorf("invalid recurrence %q: missing unit", rule)
+	}
+
+	r.unit = strings.TrimSuffix(fields[0], "s")
+	switch r.unit {
+	case recurDay, recurWeek, recurMonth, recurYear:
+	default:
+		return recurrence{}, fmt.Errorf("invalid recurrence %q: unknown unit %s", rule, fields[0])
+	}
+	fields = fields[1:]
+
+	if len(fields) == 0 {
+		return r, nil
+	}
+	if r.unit != recurWeek || fields[0] != "on" || len(fields) < 2 {
+		return recurrence{}, fmt.Errorf("invalid recurrence %q", rule)
+	}
+
+	r.weekdays = make(map[time.Weekday]bool)
+	for _, name := range strings.FieldsFunc(strings.Join(fields[1:], " "), func(c rune) bool { return c == ',' || c == ' ' }) {
+		day, ok := timeparse.Weekday(name)
+		if !ok {
+			return recurrence{}, fmt.Errorf("invalid recurrence %q: unknown weekday %s", rule, name)
+		}
+		r.weekdays[day] = true
+	}
+	if len(r.weekdays) == 0 {
+		return recurrence{}, fmt.Errorf("invalid recurrence %q: missing weekdays", rule)
+	}
+	return r, nil
+}
+
+// validateRecurrence reports whether a value is a recurrence rule
+func validateRecurrence(value interface{}) bool {
+	str, ok := value.(string)
+	if !ok {
+		return false
+	}
+	_, err := parseRecurrence(str)
+	return err == nil
+}
+
+// migrateRecurrenceCheck validates the Recurring datatype of older databases as a recurrence rule
+// instead of any nonempty text. Values stored before keep working, closing them logs the bad rule.
+func migrateRecurrenceCheck(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+        UPDATE datatypes
+        SET value_check = ?
+        WHERE name = 'Recurring'
+        AND value_check = ?
+    `, RecurrenceCheck, nonemptyCheck)
+	if err != nil {
+		return fmt.Errorf("failed to update the Recurring check: %w", err)
+	}
+	return nil
+}
+
+// addMonths adds months to t, clamping the day to the end of the resulting month
+func addMonths(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(t.Day(), lastDay)-1)
+}
+
+// startOfWeek returns midnight of the Monday of t's week
+func startOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+}
+
+// next returns the occurrence following t
+func (r recurrence) next(t time.Time) time.Time {
+	switch r.unit {
+	case recurDay:
+		return t.AddDate(0, 0, r.interval)
+	case recurMonth:
+		return addMonths(t, r.interval)
+	case recurYear:
+		return addMonths(t, 12*r.interval)
+	}
+
+	if len(r.weekdays) == 0 {
+		return t.AddDate(0, 0, 7*r.interval)
+	}
+
+	// The first listed weekday after t, in a week that is a multiple of interval away
+	week := startOfWeek(t)
+	for days := 1; ; days++ {
+		candidate := t.AddDate(0, 0, days)
+		weeks := int(startOfWeek(candidate).Sub(week).Hours()/24+0.5) / 7
+		if weeks%r.interval == 0 && r.weekdays[candidate.Weekday()] {
+			return candidate
+		}
+	}
+}
+
+// parseStoredTime parses a stored datetime value, reporting false when it is empty or malformed
+func parseStoredTime(value interface{}) (time.Time, bool) {
+	str, ok := value.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(datetimeFormat, strings.TrimSpace(str), time.Local)
+	return t, err == nil
+}
+
+// calendarDays returns the number of calendar days from a to b
+func calendarDays(a, b time.Time) int {
+	start := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	end := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(end.Sub(start).Hours() / 24)
+}
+
+// spawnRecurrence creates the next occurrence of a recurring row that was just closed.
+// The open columns are copied, with Opened and Deadline moved to the next occurrence.
+// It returns the id of the new row, or 0 when the row does not recur. A rule that does not
+// parse, as older databases accepted any text, is logged and no occurrence is scheduled.
+func (db *Database) spawnRecurrence(tx *sql.Tx, categoryName string, closed RowData, closeDate string) (int, error) {
+	rule, _ := closed["Recurring"].(string)
+	if strings.TrimSpace(rule) == "" {
+		return 0, nil
+	}
+	r, err := parseRecurrence(rule)
+	if err != nil {
+		db.logger.LogWarn("Not scheduling the next occurrence in %s: %v", categoryName, err)
+		return 0, nil
+	}
+
+	// Without an opening time the series continues from the close
+	opened, ok := parseStoredTime(closed["Opened"])
+	if !ok {
+		if opened, ok = parseStoredTime(closeDate); !ok {
+			opened = time.Now()
+		}
+	}
+	nextOpened := r.next(opened)
+
+	columns, err := getTableColumns(tx, categoryName)
+	if err != nil {
+		return 0, err
+	}
+
+	next := make(RowData)
+	for _, col := range columns {
+		dt, err := GetCategoryDatatype(tx, categoryName, col.Name)
+		if err != nil || dt.FillBehavior != Open {
+			continue
+		}
+		value := closed[col.Name]
+		if value == nil || value == "" {
+			continue
+		}
+
+		if col.Name == "Deadline" {
+			if deadline, ok := parseStoredTime(value); ok {
+				// Weekday rules keep the distance to the opening, others follow the rule
+				if len(r.weekdays) > 0 {
+					deadline = deadline.AddDate(0, 0, calendarDays(opened, nextOpened))
+				} else {
+					deadline = r.next(deadline)
+				}
+				value = deadline.Format(datetimeFormat)
+			}
+		}
+		next[col.Name] = value
+	}
+	if findColumn(columns, "Opened") != -1 {
+		next["Opened"] = nextOpened.Format(datetimeFormat)
+	}
+
+	nextID, err := db.insertRow(tx, categoryName, next)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create the next occurrence: %w", err)
+	}
+	return nextID, nil
+}
+
+// StopRecurrence ends a series by clearing the Recurring value of a row.
+// Closing the row afterwards no longer creates a next occurrence.
+func (db *Database) StopRecurrence(categoryName string, id int) error {
+	table, err := quoteCategory(categoryName)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf(failedToBeginTxString, err)
+	}
+	defer tx.Rollback()
+
+	old, err := rowSnapshot(tx, categoryName, id)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("no item found with id %d in category %s", id, categoryName)
+	}
+	if err != nil {
+		return err
+	}
+	if rule, _ := old["Recurring"].(string); strings.TrimSpace(rule) == "" {
+		return fmt.Errorf("item %d in category %s does not recur", id, categoryName)
+	}
+
+	query := fmt.Sprintf(`
+        UPDATE %s
+        SET Recurring = NULL,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE id = ?
+        AND deleted_at IS NULL
+    `, table)
+	result, err := tx.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to stop recurrence: %w", err)
+	}
+	if err := expectAffected(result, fmt.Errorf("no item found with id %d in category %s", id, categoryName)); err != nil {
+		return err
+	}
+
+	if err := indexRow(tx, categoryName, id); err != nil {
+		return err
+	}
+
+	if err := recordChange(tx, HistoryUpdate, categoryName, id, old); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,213 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		rule     string
+		unit     string
+		interval int
+		weekdays []time.Weekday
+		wantErr  bool
+	}{
+		{rule: "Daily", unit: recurDay, interval: 1},
+		{rule: "weekly", unit: recurWeek, interval: 1},
+		{rule: "every month", unit: recurMonth, interval: 1},
+		{rule: "every 3 days", unit: recurDay, interval: 3},
+		{rule: "Every 2 weeks on Mon,Thu", unit: recurWeek, interval: 2, weekdays: []time.Weekday{time.Monday, time.Thursday}},
+		{rule: "every week on friday, sunday", unit: recurWeek, interval: 1, weekdays: []time.Weekday{time.Friday, time.Sunday}},
+		{rule: "", wantErr: true},
+		{rule: "fortnightly", wantErr: true},
+		{rule: "every 0 days", wantErr: true},
+		{rule: "every 2", wantErr: true},
+		{rule: "every 2 decades", wantErr: true},
+		{rule: "every month on Mon", wantErr: true},
+		{rule: "every week on Funday", wantErr: true},
+		{rule: "every week on", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			r, err := parseRecurrence(tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRecurrence() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if r.unit != tt.unit || r.interval != tt.interval || len(r.weekdays) != len(tt.weekdays) {
+				t.Errorf("parseRecurrence() = %+v, want %s every %d on %v", r, tt.unit, tt.interval, tt.weekdays)
+			}
+			for _, day := range tt.weekdays {
+				if !r.weekdays[day] {
+					t.Errorf("parseRecurrence() weekdays = %v, missing %v", r.weekdays, day)
+				}
+			}
+		})
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	at := func(value string) time.Time {
+		parsed, err := time.ParseInLocation(datetimeFormat, value, time.Local)
+		if err != nil {
+			t.Fatalf("invalid test time %q: %v", value, err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		rule string
+		from string
+		want string
+	}{
+		{rule: "Daily", from: "2024-05-01 09:00:00", want: "2024-05-02 09:00:00"},
+		{rule: "Weekly", from: "2024-05-01 09:00:00", want: "2024-05-08 09:00:00"},
+		{rule: "Monthly", from: "2024-01-31 09:00:00", want: "2024-02-29 09:00:00"},
+		{rule: "every 2 months", from: "2024-12-15 09:00:00", want: "2025-02-15 09:00:00"},
+		{rule: "Yearly", from: "2024-02-29 09:00:00", want: "2025-02-28 09:00:00"},
+		// 2024-05-02 is a Thursday
+		{rule: "every week on Mon,Thu", from: "2024-05-02 09:00:00", want: "2024-05-06 09:00:00"},
+		{rule: "every 2 weeks on Mon,Thu", from: "2024-05-02 09:00:00", want: "2024-05-13 09:00:00"},
+		{rule: "every 2 weeks on Mon,Thu", from: "2024-05-13 09:00:00", want: "2024-05-16 09:00:00"},
+		{rule: "every 3 weeks on Thu", from: "2024-05-02 09:00:00", want: "2024-05-23 09:00:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule+" from "+tt.from, func(t *testing.T) {
+			r, err := parseRecurrence(tt.rule)
+			if err != nil {
+				t.Fatalf("parseRecurrence() error = %v", err)
+			}
+			if got := r.next(at(tt.from)).Format(datetimeFormat); got != tt.want {
+				t.Errorf("next() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// setupHabitsCategory creates a category of recurring items with a deadline
+func setupHabitsCategory(t *testing.T, db *TestDB) {
+	t.Helper()
+
+	datatypes := []Datatype{
+		{Name: "Opened", VariableType: TimeType, CompletionValue: LastCompletion, CompletionSort: LastSort, ValueCheck: nonemptyCheck, FillBehavior: Open},
+		{Name: "Closed", VariableType: TimeType, CompletionValue: DateCompletion, CompletionSort: LastSort, ValueCheck: nonemptyCheck, FillBehavior: Close},
+		{Name: "Deadline", VariableType: TimeType, CompletionValue: DateCompletion, CompletionSort: LastSort, ValueCheck: nonemptyCheck, FillBehavior: Open},
+		{Name: "Recurring", VariableType: StringType, CompletionValue: NoCompletion, CompletionSort: NoSort, ValueCheck: RecurrenceCheck, FillBehavior: Open},
+	}
+	createTestCategory(t, db, "Habits", datatypes, 1) // Note
+}
+
+func TestCloseItemSpawnsNextOccurrence(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	setupHabitsCategory(t, db)
+
+	_, err := db.CreateRow("Habits", RowData{
+		"Opened":    "2024-05-02 09:00",
+		"Deadline":  "2024-05-03 18:00",
+		"Recurring": "every 2 weeks on Mon,Thu",
+		"Note":      "Water plants",
+	})
+	if err != nil {
+		t.Fatalf("CreateRow() error = %v", err)
+	}
+	if _, err := db.CreateRow("Habits", RowData{"Opened": "2024-05-02 09:00", "Recurring": "whenever"}); err == nil {
+		t.Errorf("CreateRow() accepted an invalid recurrence")
+	}
+
+	nextID, err := db.CloseItem("Habits", 1, "2024-05-02 20:00:00")
+	if err != nil {
+		t.Fatalf("CloseItem() error = %v", err)
+	}
+	if nextID == 0 {
+		t.Fatalf("CloseItem() did not create the next occurrence")
+	}
+
+	next, err := db.ReadRow("Habits", nextID)
+	if err != nil {
+		t.Fatalf("ReadRow() error = %v", err)
+	}
+	checks := map[string]string{
+		"Opened":   "2024-05-13 09:00:00",
+		"Deadline": "2024-05-14 18:00:00",
+	}
+	for column, want := range checks {
+		got, ok := next[column].(time.Time)
+		if !ok || got.Format(datetimeFormat) != want {
+			t.Errorf("next %s = %v, want %s", column, next[column], want)
+		}
+	}
+	if next["Note"] != "Water plants" || next["Recurring"] != "every 2 weeks on Mon,Thu" {
+		t.Errorf("next occurrence did not copy the open columns: %v", next)
+	}
+	if next["Closed"] != nil {
+		t.Errorf("next occurrence is already closed: %v", next["Closed"])
+	}
+
+	pointers := pendingPointers(t, db)
+	if len(pointers) != 1 || !pointers[ItemRef{Category: "Habits", ID: nextID}.String()] {
+		t.Errorf("pending = %v, want only the next occurrence", pointers)
+	}
+
+	// A stopped series ends with its current occurrence
+	if err := db.StopRecurrence("Habits", nextID); err != nil {
+		t.Fatalf("StopRecurrence() error = %v", err)
+	}
+	if err := db.StopRecurrence("Habits", nextID); err == nil {
+		t.Errorf("StopRecurrence() of an item that does not recur succeeded")
+	}
+	lastID, err := db.CloseItem("Habits", nextID, "2024-05-13 20:00:00")
+	if err != nil {
+		t.Fatalf("CloseItem() error = %v", err)
+	}
+	if lastID != 0 {
+		t.Errorf("CloseItem() after StopRecurrence() created item %d", lastID)
+	}
+}
+
+func TestCloseItemWithLegacyRecurrence(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	// Databases before 1.7.0 accepted any nonempty text as a rule
+	datatypes := []Datatype{
+		{Name: "Opened", VariableType: TimeType, CompletionValue: LastCompletion, CompletionSort: LastSort, ValueCheck: nonemptyCheck, FillBehavior: Open},
+		{Name: "Closed", VariableType: TimeType, CompletionValue: DateCompletion, CompletionSort: LastSort, ValueCheck: nonemptyCheck, FillBehavior: Close},
+		{Name: "Recurring", VariableType: StringType, CompletionValue: NoCompletion, CompletionSort: NoSort, ValueCheck: nonemptyCheck, FillBehavior: Open},
+	}
+	createTestCategory(t, db, "Habits", datatypes, 1) // Note
+
+	id, err := db.CreateRow("Habits", RowData{"Opened": "2024-05-02 09:00", "Recurring": "fortnightly"})
+	if err != nil {
+		t.Fatalf("CreateRow() error = %v", err)
+	}
+	nextID, err := db.CloseItem("Habits", id, "2024-05-02 20:00:00")
+	if err != nil {
+		t.Fatalf("CloseItem() with an invalid rule error = %v", err)
+	}
+	if nextID != 0 {
+		t.Errorf("CloseItem() scheduled item %d from an invalid rule", nextID)
+	}
+	if pointers := pendingPointers(t, db); len(pointers) != 0 {
+		t.Errorf("pending = %v, want the item closed", pointers)
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	if err := migrateRecurrenceCheck(tx); err != nil {
+		t.Fatalf("migrateRecurrenceCheck() error = %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit migration: %v", err)
+	}
+	if _, err := db.CreateRow("Habits", RowData{"Opened": "2024-05-02 09:00", "Recurring": "fortnightly"}); err == nil {
+		t.Errorf("CreateRow() accepted an invalid rule after the migration")
+	}
+}
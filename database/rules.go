@@ -0,0 +1,448 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ruleNode is a parsed check or completion rule, such as "range(1,10)" or "and(nonempty, max_length(20))".
+// Arguments are either nested rules or literal values.
+type ruleNode struct {
+	name    string // rule name, or the text of a literal
+	args    []*ruleNode
+	call    bool // written with parentheses
+	literal bool // plain value rather than a rule
+	quoted  bool // literal written between quotes
+
+	// values precomputed by checkRule
+	bounds []float64
+	re     *regexp.Regexp
+}
+
+// String renders the rule back into the grammar accepted by parseRule
+func (n *ruleNode) String() string {
+	if n.literal {
+		return quoteRuleArg(n.name)
+	}
+	if !n.call {
+		return n.name
+	}
+	args := make([]string, len(n.args))
+	for i, arg := range n.args {
+		args[i] = arg.String()
+	}
+	return n.name + "(" + strings.Join(args, ",") + ")"
+}
+
+// argStrings returns the text of every argument
+func (n *ruleNode) argStrings() []string {
+	if !n.call {
+		return nil
+	}
+	args := make([]string, len(n.args))
+	for i, arg := range n.args {
+		if arg.literal {
+			args[i] = arg.name
+		} else {
+			args[i] = arg.String()
+		}
+	}
+	return args
+}
+
+// quoteRuleArg quotes an argument when it could not be read back as a bare value
+func quoteRuleArg(arg string) string {
+	if arg != "" && strings.TrimSpace(arg) == arg && !strings.ContainsAny(arg, `,()"'\`) {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// ruleParser reads the rule grammar:
+//
+//	rule  = name [ "(" [ arg { "," arg } ] ")" ]
+//	arg   = rule | quoted | bare
+//
+// Quoted values use single or double quotes with backslash escapes.
+// Bare values run until the next comma or parenthesis and are trimmed,
+// so in(Not Started,In Progress) works without quotes.
+type ruleParser struct {
+	src []rune
+	pos int
+}
+
+// parseRule parses a rule without checking its names and arguments
+func parseRule(src string) (*ruleNode, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, fmt.Errorf("empty rule")
+	}
+	p := &ruleParser{src: []rune(src)}
+	node, err := p.parseArg()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos != len(p.src) {
+		return nil, p.errorf("unexpected %q", p.src[p.pos])
+	}
+	if node.quoted {
+		return nil, fmt.Errorf("expected a rule, got the string %q", node.name)
+	}
+
+	// a lone word is a rule without arguments
+	node.literal = false
+	return node, nil
+}
+
+func (p *ruleParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("position %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *ruleParser) skipSpaces() {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *ruleParser) peek() rune {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *ruleParser) parseArg() (*ruleNode, error) {
+	p.skipSpaces()
+	if r := p.peek(); r == '"' || r == '\'' {
+		return p.parseQuoted()
+	}
+
+	start := p.pos
+	for p.pos < len(p.src) && !strings.ContainsRune(",()", p.src[p.pos]) {
+		p.pos++
+	}
+	text := strings.TrimSpace(string(p.src[start:p.pos]))
+
+	if p.peek() != '(' {
+		if text == "" {
+			return nil, p.errorf("expected a value")
+		}
+		return &ruleNode{name: text, literal: true}, nil
+	}
+
+	if !isRuleName(text) {
+		return nil, p.errorf("invalid rule name %q", text)
+	}
+	node := &ruleNode{name: text, call: true}
+
+	p.pos++ // (
+	p.skipSpaces()
+	if p.peek() == ')' {
+		p.pos++
+		return node, nil
+	}
+
+	for {
+		arg, err := p.parseArg()
+		if err != nil {
+			return nil, err
+		}
+		node.args = append(node.args, arg)
+
+		p.skipSpaces()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return node, nil
+		case 0:
+			return nil, p.errorf("missing closing parenthesis for %s", node.name)
+		default:
+			return nil, p.errorf("unexpected %q", p.peek())
+		}
+	}
+}
+
+func (p *ruleParser) parseQuoted() (*ruleNode, error) {
+	quote := p.src[p.pos]
+	p.pos++
+
+	var sb strings.Builder
+	for p.pos < len(p.src) {
+		r := p.src[p.pos]
+		p.pos++
+		switch {
+		case r == '\\' && p.pos < len(p.src):
+			sb.WriteRune(p.src[p.pos])
+			p.pos++
+		case r == quote:
+			return &ruleNode{name: sb.String(), literal: true, quoted: true}, nil
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return nil, p.errorf("unterminated string")
+}
+
+// isRuleName reports whether text can name a rule
+func isRuleName(text string) bool {
+	if text == "" {
+		return false
+	}
+	for _, r := range text {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// compileCheck parses a ValueCheck and checks its rule names and arguments
+func compileCheck(src string) (*ruleNode, error) {
+	node, err := parseRule(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid check %q: %w", src, err)
+	}
+	if err := checkRule(node); err != nil {
+		return nil, fmt.Errorf("invalid check %q: %w", src, err)
+	}
+	return node, nil
+}
+
+// checkRule verifies a parsed check and precomputes its numeric bounds and patterns
+func checkRule(n *ruleNode) error {
+	if n.quoted {
+		return fmt.Errorf("expected a rule, got the string %q", n.name)
+	}
+	// bare words inside and/or/not are rules without arguments
+	n.literal = false
+
+	literalArgs := func() error {
+		for _, arg := range n.args {
+			if !arg.literal {
+				return fmt.Errorf("%s expects values, got the rule %s", n.name, arg)
+			}
+		}
+		return nil
+	}
+
+	switch n.name {
+	case nonemptyCheck, NoCheck, URLCheck, MailCheck, PhoneCheck, FileCheck, DateCheck, RecurrenceCheck:
+		if len(n.args) != 0 {
+			return fmt.Errorf("check %s takes no arguments", n.name)
+		}
+	case RangeCheck:
+		if len(n.args) != 2 {
+			return fmt.Errorf("check %s needs exactly two bounds", RangeCheck)
+		}
+		if err := literalArgs(); err != nil {
+			return err
+		}
+		min, err := strconv.ParseFloat(n.args[0].name, 64)
+		if err != nil {
+			return fmt.Errorf("invalid lower bound %q", n.args[0].name)
+		}
+		max, err := strconv.ParseFloat(n.args[1].name, 64)
+		if err != nil {
+			return fmt.Errorf("invalid upper bound %q", n.args[1].name)
+		}
+		if min > max {
+			return fmt.Errorf("lower bound %v is greater than upper bound %v", min, max)
+		}
+		n.bounds = []float64{min, max}
+	case SetCheck:
+		if len(n.args) == 0 {
+			return fmt.Errorf("check %s needs at least one value", SetCheck)
+		}
+		return literalArgs()
+	case RegexCheck:
+		if len(n.args) != 1 {
+			return fmt.Errorf("check %s needs exactly one pattern", RegexCheck)
+		}
+		if err := literalArgs(); err != nil {
+			return err
+		}
+		re, err := regexp.Compile("^(?:" + n.args[0].name + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", n.args[0].name, err)
+		}
+		n.re = re
+	case MinLengthCheck, MaxLengthCheck:
+		if len(n.args) != 1 {
+			return fmt.Errorf("check %s needs exactly one length", n.name)
+		}
+		if err := literalArgs(); err != nil {
+			return err
+		}
+		length, err := strconv.Atoi(n.args[0].name)
+		if err != nil || length < 0 {
+			return fmt.Errorf("invalid length %q", n.args[0].name)
+		}
+		n.bounds = []float64{float64(length)}
+	case AndCheck, OrCheck:
+		if len(n.args) == 0 {
+			return fmt.Errorf("check %s needs at least one rule", n.name)
+		}
+		for _, arg := range n.args {
+			if err := checkRule(arg); err != nil {
+				return err
+			}
+		}
+	case NotCheck:
+		if len(n.args) != 1 {
+			return fmt.Errorf("check %s needs exactly one rule", NotCheck)
+		}
+		return checkRule(n.args[0])
+	default:
+		return fmt.Errorf("unknown check %q", n.name)
+	}
+	return nil
+}
+
+// eval checks a value against the rule, returning nil when it is valid
+func (n *ruleNode) eval(value interface{}) *ValidationError {
+	var valid bool
+	var message string
+
+	switch n.name {
+	case nonemptyCheck:
+		valid = validateNonempty(value)
+		message = "must not be empty"
+	case NoCheck:
+		valid = true
+	case RangeCheck:
+		valid = validateInRange(value, n.bounds[0], n.bounds[1])
+		message = fmt.Sprintf("must be a number between %v and %v", n.bounds[0], n.bounds[1])
+	case SetCheck:
+		args := n.argStrings()
+		valid = validateInSet(value, args)
+		message = fmt.Sprintf("must be one of %s", strings.Join(args, ", "))
+	case URLCheck:
+		valid = validateURL(value)
+		message = "must be a URL such as https://example.com"
+	case MailCheck:
+		valid = validateEmail(value)
+		message = "must be an email address"
+	case PhoneCheck:
+		valid = validatePhone(value)
+		message = "must be a phone number of 7 to 15 digits"
+	case FileCheck:
+		valid = validateFileExists(value)
+		message = "must be the path of an existing file"
+	case DateCheck:
+		valid = validateDate(value)
+		message = fmt.Sprintf("must be a date like %s or tomorrow 9am", datetimeFormat)
+	case RecurrenceCheck:
+		valid = validateRecurrence(value)
+		message = `must be Daily, Weekly, Monthly, Yearly or a rule like "every 2 weeks on Mon,Thu"`
+	case RegexCheck:
+		valid = validateRegex(value, n.re)
+		message = fmt.Sprintf("must match %s", n.args[0].name)
+	case MinLengthCheck:
+		length, ok := valueLength(value)
+		valid = ok && length >= int(n.bounds[0])
+		message = fmt.Sprintf("must be at least %d characters long", int(n.bounds[0]))
+	case MaxLengthCheck:
+		length, ok := valueLength(value)
+		valid = ok && length <= int(n.bounds[0])
+		message = fmt.Sprintf("must be at most %d characters long", int(n.bounds[0]))
+	case AndCheck:
+		for _, arg := range n.args {
+			if verr := arg.eval(value); verr != nil {
+				return verr
+			}
+		}
+		return nil
+	case OrCheck:
+		messages := make([]string, 0, len(n.args))
+		for _, arg := range n.args {
+			verr := arg.eval(value)
+			if verr == nil {
+				return nil
+			}
+			messages = append(messages, verr.Message)
+		}
+		message = strings.Join(messages, ", or ")
+	case NotCheck:
+		valid = n.args[0].eval(value) != nil
+		message = fmt.Sprintf("must not satisfy %s", n.args[0])
+	default:
+		message = fmt.Sprintf("has an unknown check %q", n.name)
+	}
+
+	if valid {
+		return nil
+	}
+	return &ValidationError{
+		Rule:    n.name,
+		Args:    n.argStrings(),
+		Value:   value,
+		Message: message,
+	}
+}
+
+// requiresDate reports whether every value accepted by the rule is a date
+func (n *ruleNode) requiresDate() bool {
+	switch n.name {
+	case DateCheck:
+		return true
+	case AndCheck:
+		for _, arg := range n.args {
+			if arg.requiresDate() {
+				return true
+			}
+		}
+	case OrCheck:
+		for _, arg := range n.args {
+			if !arg.requiresDate() {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// valueLength returns the length of a text in characters, or of a list in items
+func valueLength(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case string:
+		return utf8.RuneCountInString(v), true
+	case []string:
+		return len(v), true
+	case nil:
+		return 0, true
+	case time.Time:
+		return 0, false
+	default:
+		return utf8.RuneCountInString(fmt.Sprint(v)), true
+	}
+}
+
+// checkResult is the outcome of compiling one ValueCheck
+type checkResult struct {
+	node *ruleNode
+	err  error
+}
+
+// compiledChecks caches compiled ValueChecks by their text. Datatypes are read fresh from the
+// database for every row, so the cache cannot live on them.
+var compiledChecks sync.Map // map[string]checkResult
+
+// compiledCheck returns the parsed ValueCheck of the datatype, parsing each check text only once
+func (dt *Datatype) compiledCheck() (*ruleNode, error) {
+	if cached, ok := compiledChecks.Load(dt.ValueCheck); ok {
+		c := cached.(checkResult)
+		return c.node, c.err
+	}
+	node, err := compileCheck(dt.ValueCheck)
+	compiledChecks.Store(dt.ValueCheck, checkResult{node: node, err: err})
+	return node, err
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		want    string
+		wantErr bool
+	}{
+		{name: "bare name", rule: "nonempty", want: "nonempty"},
+		{name: "arguments", rule: "range(1, 10)", want: "range(1,10)"},
+		{name: "spaces in bare values", rule: "in(Not Started, In Progress)", want: `in(Not Started,In Progress)`},
+		{name: "quoted comma", rule: `regex("[a-z]{2,5}")`, want: `regex("[a-z]{2,5}")`},
+		{name: "single quotes and escapes", rule: `in('it\'s', "a \"b\"")`, want: `in("it's","a \"b\"")`},
+		{name: "nested", rule: "and(nonempty, or(url, mail))", want: "and(nonempty,or(url,mail))"},
+		{name: "empty", rule: " ", wantErr: true},
+		{name: "missing parenthesis", rule: "range(1,10", wantErr: true},
+		{name: "trailing text", rule: "range(1,10) x", wantErr: true},
+		{name: "unterminated string", rule: `regex("abc)`, wantErr: true},
+		{name: "quoted rule", rule: `"nonempty"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := parseRule(tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRule(%q) error = %v, wantErr %v", tt.rule, err, tt.wantErr)
+			}
+			if !tt.wantErr && node.String() != tt.want {
+				t.Errorf("parseRule(%q) = %s, want %s", tt.rule, node, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		check   string
+		wantErr bool
+	}{
+		{name: "float range", check: "range(-0.5, 2.5)"},
+		{name: "composite", check: "and(nonempty, not(in(TBD)), max_length(20))"},
+		{name: "regex", check: `regex("^[A-Z]{3}-\\d+$")`},
+		{name: "unknown check", check: "positive", wantErr: true},
+		{name: "unknown nested check", check: "or(url, positive)", wantErr: true},
+		{name: "arguments on a plain check", check: "url(x)", wantErr: true},
+		{name: "invalid bound", check: "range(a,10)", wantErr: true},
+		{name: "invalid regex", check: `regex("[a-")`, wantErr: true},
+		{name: "negative length", check: "min_length(-1)", wantErr: true},
+		{name: "empty and", check: "and()", wantErr: true},
+		{name: "not with two rules", check: "not(url,mail)", wantErr: true},
+		{name: "rule as set value", check: "in(range(1,2))", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compileCheck(tt.check)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("compileCheck(%q) error = %v, wantErr %v", tt.check, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRuleEval(t *testing.T) {
+	tests := []struct {
+		name     string
+		check    string
+		value    interface{}
+		wantRule string
+	}{
+		{name: "regex match", check: `regex("[A-Z]{2,3}-\\d+")`, value: "AB-12"},
+		{name: "regex is anchored", check: `regex("[A-Z]{2,3}-\\d+")`, value: "xAB-12", wantRule: RegexCheck},
+		{name: "regex alternatives are anchored", check: "regex(a|b)", value: "ab", wantRule: RegexCheck},
+		{name: "min length counts characters", check: "min_length(3)", value: "été"},
+		{name: "min length too short", check: "min_length(3)", value: "ab", wantRule: MinLengthCheck},
+		{name: "max length of a list", check: "max_length(2)", value: []string{"a", "b", "c"}, wantRule: MaxLengthCheck},
+		{name: "float range", check: "range(0.5,1.5)", value: 1.25},
+		{name: "and reports first failure", check: "and(nonempty, max_length(2))", value: "abc", wantRule: MaxLengthCheck},
+		{name: "or passes", check: "or(url, mail)", value: "me@example.com"},
+		{name: "or fails", check: "or(url, mail)", value: "nope", wantRule: OrCheck},
+		{name: "not", check: "not(in(TBD, TODO))", value: "TBD", wantRule: NotCheck},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dt := Datatype{Name: "Field", ValueCheck: tt.check}
+			verr := dt.Validate(tt.value)
+			if tt.wantRule == "" {
+				if verr != nil {
+					t.Errorf("Validate() = %v, want nil", verr)
+				}
+				return
+			}
+			if verr == nil || verr.Rule != tt.wantRule || verr.Field != "Field" {
+				t.Errorf("Validate() = %+v, want a %s error", verr, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestCompiledCheckIsCached(t *testing.T) {
+	dt := Datatype{Name: "Field", ValueCheck: "max_length(3)"}
+	first, err := dt.compiledCheck()
+	if err != nil {
+		t.Fatalf("compiledCheck() error = %v", err)
+	}
+	if second, _ := dt.compiledCheck(); second != first {
+		t.Error("compiledCheck() parsed the same check twice")
+	}
+	// Another datatype with the same check shares the compiled rule
+	other := Datatype{Name: "Other", ValueCheck: "max_length(3)"}
+	if shared, _ := other.compiledCheck(); shared != first {
+		t.Error("compiledCheck() parsed the same check again for another datatype")
+	}
+
+	dt.ValueCheck = "min_length(3)"
+	if third, _ := dt.compiledCheck(); third == first || third.name != MinLengthCheck {
+		t.Error("compiledCheck() did not follow a changed ValueCheck")
+	}
+}
+
+func TestComposeArgumentsQuotes(t *testing.T) {
+	check := ComposeArguments(RegexCheck, "[a-z]{2,5}")
+	node, err := compileCheck(check)
+	if err != nil {
+		t.Fatalf("compileCheck(%q) error = %v", check, err)
+	}
+	if got := node.argStrings(); len(got) != 1 || got[0] != "[a-z]{2,5}" {
+		t.Errorf("arguments = %q, want the pattern back", got)
+	}
+}
@@ -16,8 +16,16 @@ func createCategoryTables(tx *sql.Tx, categories []CategoryTemplate) error {
 			return fmt.Errorf("failed to get column definitions for category %s: %v", cat.Name, err)
 		}
 
-		// Create table with standard CRUD columns and dynamic columns
-		createTableSQL := fmt.Sprintf(`
+		if err := createCategoryTable(tx, cat.Name, columnDefs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createCategoryTable creates a single table with the standard CRUD columns and the given column definitions
+func createCategoryTable(tx *sql.Tx, name string, columnDefs []string) error {
+	createTableSQL := fmt.Sprintf(`
             CREATE TABLE %s (
                 id INTEGER PRIMARY KEY AUTOINCREMENT,
                 created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
@@ -25,12 +33,11 @@ func createCategoryTables(tx *sql.Tx, categories []CategoryTemplate) error {
                 deleted_at DATETIME,
                 %s
             )
-        `, cat.Name, strings.Join(columnDefs, ",\n"))
+        `, quoteIdentifier(name), strings.Join(columnDefs, ",\n"))
 
-		_, err = tx.Exec(createTableSQL)
-		if err != nil {
-			return fmt.Errorf("failed to create table %s: %v", cat.Name, err)
-		}
+	_, err := tx.Exec(createTableSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create table %s: %v", name, err)
 	}
 	return nil
 }
@@ -64,7 +71,7 @@ func getColumnDefinitions(tx *sql.Tx, columnIDs []int) ([]string, error) {
 			return nil, err
 		}
 
-		columnDefs = append(columnDefs, fmt.Sprintf("%s %s", datatype.Name, sqliteType))
+		columnDefs = append(columnDefs, fmt.Sprintf("%s %s", quoteIdentifier(datatype.Name), sqliteType))
 	}
 
 	return columnDefs, nil
@@ -90,19 +97,16 @@ func toSQLiteType(goType string) (string, error) {
 	}
 }
 
+// GetCategories returns the names of the active categories
 func (data *Database) GetCategories() ([]string, error) {
-	// Query for table names
 	rows, err := data.DB.Query(`
 		SELECT name
-		FROM sqlite_master
-		WHERE type = 'table'
-		  AND name NOT LIKE 'sqlite_%'
-		  AND name NOT LIKE 'datatypes'
-		  AND name NOT LIKE 'metadata'
+		FROM categories
+		WHERE deleted_at IS NULL
 		ORDER BY name
 	`)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query table names: %w", err)
+		return nil, fmt.Errorf("failed to query category names: %w", err)
 	}
 	defer rows.Close()
 
@@ -110,10 +114,7 @@ func (data *Database) GetCategories() ([]string, error) {
 	for rows.Next() {
 		var name string
 		if err := rows.Scan(&name); err != nil {
-			return nil, fmt.Errorf("failed to scan table name: %w", err)
-		}
-		if name == "pending" {
-			continue
+			return nil, fmt.Errorf("failed to scan category name: %w", err)
 		}
 		categories = append(categories, name)
 	}
@@ -155,3 +156,171 @@ func (data *Database) GetCategoryColumns(categoryName string) ([]string, error)
 
 	return columns, nil
 }
+
+// reservedTableNames lists the tables used internally that can never be categories
+var reservedTableNames = map[string]bool{
+	"categories":         true,
+	"datatype_overrides": true,
+	"datatypes":          true,
+	"history":            true,
+	"links":              true,
+	"metadata":           true,
+	"migrations":         true,
+	"pending":            true,
+	searchIndexTable:     true,
+}
+
+// isReservedTableName reports whether a table name belongs to SQLite or the application
+func isReservedTableName(name string) bool {
+	lower := strings.ToLower(name)
+	return reservedTableNames[lower] ||
+		strings.HasPrefix(lower, "sqlite_") ||
+		strings.HasPrefix(lower, searchIndexTable+"_")
+}
+
+// validateCategoryName checks that a name can be used as a category table
+func validateCategoryName(name string) error {
+	if err := validateIdentifier(name); err != nil {
+		return fmt.Errorf("invalid category name: %w", err)
+	}
+	if isReservedTableName(name) {
+		return fmt.Errorf("category name %q is reserved", name)
+	}
+	return nil
+}
+
+// tableExists reports whether a table with the given name exists
+func tableExists(tx *sql.Tx, name string) (bool, error) {
+	var count int
+	err := tx.QueryRow(`
+		SELECT COUNT(*)
+		FROM sqlite_master
+		WHERE type = 'table'
+		  AND name = ? COLLATE NOCASE
+	`, name).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up table %s: %w", name, err)
+	}
+	return count > 0, nil
+}
+
+// storedTableName returns the name of a table as it was created. SQLite ignores the case of
+// table names, so callers may spell them differently.
+func storedTableName(tx *sql.Tx, name string) (string, error) {
+	var stored string
+	err := tx.QueryRow(`
+		SELECT name
+		FROM sqlite_master
+		WHERE type = 'table'
+		  AND name = ? COLLATE NOCASE
+	`, name).Scan(&stored)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("table %s does not exist", name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up table %s: %w", name, err)
+	}
+	return stored, nil
+}
+
+// CreateCategory creates a new category table in an existing database
+func (db *Database) CreateCategory(template CategoryTemplate) error {
+	if err := validateCategoryName(template.Name); err != nil {
+		return err
+	}
+	if len(template.ColumnsID) == 0 {
+		return fmt.Errorf("category %s has no columns", template.Name)
+	}
+
+	seen := make(map[int]bool)
+	for _, id := range template.ColumnsID {
+		if seen[id] {
+			return fmt.Errorf("datatype %d is listed more than once", id)
+		}
+		seen[id] = true
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf(failedToBeginTxString, err)
+	}
+	defer tx.Rollback()
+
+	exists, err := tableExists(tx, template.Name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("category %s already exists", template.Name)
+	}
+
+	if err := createCategoryTables(tx, []CategoryTemplate{template}); err != nil {
+		return err
+	}
+
+	if err := registerCategory(tx, template); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteCategory drops a category table together with its pending items and links.
+// Use ArchiveCategory to hide a category while keeping its data.
+func (db *Database) DeleteCategory(categoryName string) error {
+	if err := validateCategoryName(categoryName); err != nil {
+		return err
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf(failedToBeginTxString, err)
+	}
+	defer tx.Rollback()
+
+	exists, err := tableExists(tx, categoryName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("category %s does not exist", categoryName)
+	}
+
+	// The history is append-only, it keeps the last values of every dropped row
+	ids, err := queryIDs(tx, fmt.Sprintf("SELECT id FROM %s ORDER BY id", quoteIdentifier(categoryName)))
+	if err != nil {
+		return fmt.Errorf("failed to query rows of %s: %w", categoryName, err)
+	}
+	for _, id := range ids {
+		if err := recordPurge(tx, categoryName, id); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf("DROP TABLE %s", quoteIdentifier(categoryName))); err != nil {
+		return fmt.Errorf("failed to drop table %s: %w", categoryName, err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM categories WHERE name = ?", categoryName); err != nil {
+		return fmt.Errorf("failed to unregister category %s: %w", categoryName, err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM datatype_overrides WHERE category = ?", categoryName); err != nil {
+		return fmt.Errorf("failed to remove datatype overrides of %s: %w", categoryName, err)
+	}
+
+	// Forget the pending items of the dropped category
+	if err := db.removeCategoryFromPending(tx, categoryName); err != nil {
+		return err
+	}
+
+	if err := unindexCategory(tx, categoryName); err != nil {
+		return err
+	}
+
+	if err := removeCategoryLinks(tx, categoryName); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
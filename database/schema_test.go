@@ -0,0 +1,120 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestCreateCategory(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	tests := []struct {
+		name     string
+		template CategoryTemplate
+		wantErr  bool
+	}{
+		{
+			name:     "valid category",
+			template: CategoryTemplate{Name: "Books", ColumnsID: []int{1, 2}}, // Note, Project
+			wantErr:  false,
+		},
+		{
+			name:     "existing category",
+			template: CategoryTemplate{Name: "General", ColumnsID: []int{1}},
+			wantErr:  true,
+		},
+		{
+			name:     "reserved name",
+			template: CategoryTemplate{Name: "pending", ColumnsID: []int{1}},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid name",
+			template: CategoryTemplate{Name: "Books\nDROP TABLE General", ColumnsID: []int{1}},
+			wantErr:  true,
+		},
+		{
+			name:     "no columns",
+			template: CategoryTemplate{Name: "Workouts", ColumnsID: nil},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown datatype",
+			template: CategoryTemplate{Name: "Workouts", ColumnsID: []int{99}},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := db.CreateCategory(tt.template)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CreateCategory() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// The new category must be usable straight away
+	if _, err := db.CreateRow("Books", RowData{"Note": "Dune", "Project": "Reading"}); err != nil {
+		t.Errorf("CreateRow() on new category error = %v", err)
+	}
+}
+
+func TestDeleteCategory(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	if err := db.CreateCategory(CategoryTemplate{Name: "Books", ColumnsID: []int{1}}); err != nil {
+		t.Fatalf("Failed to create test category: %v", err)
+	}
+	if _, err := db.CreateRow("Books", RowData{"Note": "Dune"}); err != nil {
+		t.Fatalf("Failed to create test row: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		category string
+		wantErr  bool
+	}{
+		{name: "existing category", category: "Books", wantErr: false},
+		{name: "already deleted category", category: "Books", wantErr: true},
+		{name: "reserved table", category: "datatypes", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := db.DeleteCategory(tt.category)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DeleteCategory() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	categories, err := db.GetCategories()
+	if err != nil {
+		t.Fatalf("GetCategories() error = %v", err)
+	}
+	for _, category := range categories {
+		if category == "Books" {
+			t.Errorf("GetCategories() still lists deleted category")
+		}
+	}
+
+	// The history keeps the creation and records the purge of the dropped row
+	var operations []string
+	rows, err := db.DB.Query("SELECT operation FROM history WHERE category = 'Books' ORDER BY id")
+	if err != nil {
+		t.Fatalf("failed to query history: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var operation string
+		if err := rows.Scan(&operation); err != nil {
+			t.Fatalf("failed to scan history: %v", err)
+		}
+		operations = append(operations, operation)
+	}
+	if len(operations) != 2 || operations[0] != HistoryCreate || operations[1] != HistoryPurge {
+		t.Errorf("history of the deleted category = %v, want create and purge", operations)
+	}
+}
@@ -0,0 +1,328 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// searchIndexTable is the full-text index over the text columns of every category.
+// SQLite creates shadow tables named after it, e.g. search_index_content.
+const searchIndexTable = "search_index"
+
+// SearchHit is a row of a category matching a search
+type SearchHit struct {
+	Category string
+	ID       int
+	Snippet  string  // matching text, with the matched terms between [ and ]
+	Rank     float64 // lower is a better match
+}
+
+// createSearchIndex creates the full-text index and fills it with the existing rows.
+// FTS5 is only available when the driver is built with the sqlite_fts5 tag,
+// otherwise the index falls back to FTS4 which every build includes.
+func createSearchIndex(tx *sql.Tx) error {
+	if _, err := tx.Exec(`SAVEPOINT fts5_probe`); err != nil {
+		return fmt.Errorf("failed to create search index: %w", err)
+	}
+	_, err := tx.Exec(`
+		CREATE VIRTUAL TABLE search_index USING fts5(
+			category UNINDEXED,
+			item_id UNINDEXED,
+			content
+		)
+	`)
+	if err != nil {
+		if _, rbErr := tx.Exec(`ROLLBACK TO fts5_probe`); rbErr != nil {
+			return fmt.Errorf("failed to create search index: %w", rbErr)
+		}
+		_, err = tx.Exec(`
+			CREATE VIRTUAL TABLE search_index USING fts4(
+				category,
+				item_id,
+				content,
+				notindexed=category,
+				notindexed=item_id
+			)
+		`)
+	}
+	if _, relErr := tx.Exec(`RELEASE fts5_probe`); relErr != nil && err == nil {
+		err = relErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create search index: %w", err)
+	}
+
+	categories, err := categoryTableNames(tx)
+	if err != nil {
+		return err
+	}
+	for _, category := range categories {
+		if err := reindexCategory(tx, category); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// categoryTableNames returns the names of every registered category, archived ones included
+func categoryTableNames(tx *sql.Tx) ([]string, error) {
+	rows, err := tx.Query(`SELECT name FROM categories ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query categories: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan category name: %w", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return names, nil
+}
+
+// searchIndexUsesFTS5 reports whether the search index was created with FTS5
+func searchIndexUsesFTS5(q queryer) (bool, error) {
+	rows, err := q.Query(`SELECT sql FROM sqlite_master WHERE name = ?`, searchIndexTable)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up search index: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return false, fmt.Errorf("search index does not exist")
+	}
+	var definition string
+	if err := rows.Scan(&definition); err != nil {
+		return false, fmt.Errorf("failed to scan search index definition: %w", err)
+	}
+	return strings.Contains(strings.ToLower(definition), "fts5"), nil
+}
+
+// textColumns returns the columns of a category holding text, in name order
+func textColumns(tx *sql.Tx, categoryName string) ([]string, error) {
+	types, err := getColumnTypes(tx, categoryName)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []string
+	for col, variableType := range types {
+		if variableType == StringType || variableType == csvType {
+			columns = append(columns, col)
+		}
+	}
+	sort.Strings(columns)
+	return columns, nil
+}
+
+// unindexRow removes a row from the search index
+func unindexRow(tx *sql.Tx, categoryName string, id int) error {
+	_, err := tx.Exec(`DELETE FROM search_index WHERE category = ? AND item_id = ?`, categoryName, id)
+	if err != nil {
+		return fmt.Errorf("failed to remove row %d of %s from the search index: %w", id, categoryName, err)
+	}
+	return nil
+}
+
+// indexRow stores the current text of a row in the search index.
+// Deleted or missing rows are removed from the index.
+func indexRow(tx *sql.Tx, categoryName string, id int) error {
+	if err := unindexRow(tx, categoryName, id); err != nil {
+		return err
+	}
+
+	columns, err := textColumns(tx, categoryName)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+
+	table, err := quoteCategory(categoryName)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE id = ? AND deleted_at IS NULL",
+		strings.Join(quoteIdentifiers(columns), ", "),
+		table,
+	)
+
+	values := make([]sql.NullString, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	err = tx.QueryRow(query, id).Scan(pointers...)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read row %d of %s for the search index: %w", id, categoryName, err)
+	}
+
+	var parts []string
+	for _, v := range values {
+		if v.Valid && strings.TrimSpace(v.String) != "" {
+			parts = append(parts, v.String)
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO search_index (category, item_id, content) VALUES (?, ?, ?)`,
+		categoryName, id, strings.Join(parts, "\n"),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to index row %d of %s: %w", id, categoryName, err)
+	}
+	return nil
+}
+
+// unindexCategory removes every row of a category from the search index
+func unindexCategory(tx *sql.Tx, categoryName string) error {
+	_, err := tx.Exec(`DELETE FROM search_index WHERE category = ?`, categoryName)
+	if err != nil {
+		return fmt.Errorf("failed to remove %s from the search index: %w", categoryName, err)
+	}
+	return nil
+}
+
+// reindexCategory rebuilds the search index entries of a category
+func reindexCategory(tx *sql.Tx, categoryName string) error {
+	if err := unindexCategory(tx, categoryName); err != nil {
+		return err
+	}
+
+	table, err := quoteCategory(categoryName)
+	if err != nil {
+		return err
+	}
+	rows, err := tx.Query(fmt.Sprintf("SELECT id FROM %s WHERE deleted_at IS NULL", table))
+	if err != nil {
+		return fmt.Errorf("failed to list rows of %s: %w", categoryName, err)
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan row id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	for _, id := range ids {
+		if err := indexRow(tx, categoryName, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// searchTerms turns free text into an FTS query matching every word as a prefix,
+// so punctuation in the input can never be read as query syntax.
+// FTS5 expects the prefix marker after the quoted word, FTS4 inside it.
+func searchTerms(text string, fts5 bool) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := make([]string, len(words))
+	for i, word := range words {
+		if fts5 {
+			terms[i] = `"` + word + `"*`
+		} else {
+			terms[i] = `"` + word + `*"`
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// Search finds the rows of every category whose text columns contain all the words of text,
+// best matches first. At most limit hits are returned.
+func (db *Database) Search(text string, limit int) ([]SearchHit, error) {
+	fts5, err := searchIndexUsesFTS5(db.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	match := searchTerms(text, fts5)
+	if match == "" {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = -1 // no limit
+	}
+
+	// FTS4 has no ranking function, it is ranked below by the number of matched terms
+	query := `
+		SELECT category, item_id, snippet(search_index, 2, '[', ']', '…', 12), bm25(search_index)
+		FROM search_index
+		WHERE search_index MATCH ?
+		ORDER BY bm25(search_index)
+		LIMIT ?
+	`
+	if !fts5 {
+		query = `
+			SELECT category, item_id, snippet(search_index, '[', ']', '…', 2, 12), offsets(search_index)
+			FROM search_index
+			WHERE search_index MATCH ?
+		`
+	}
+
+	args := []interface{}{match}
+	if fts5 {
+		args = append(args, limit)
+	}
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+	defer rows.Close()
+
+	var hits []SearchHit
+	for rows.Next() {
+		var hit SearchHit
+		var rank interface{}
+		if err := rows.Scan(&hit.Category, &hit.ID, &hit.Snippet, &rank); err != nil {
+			return nil, fmt.Errorf("failed to scan search hit: %w", err)
+		}
+		switch r := rank.(type) {
+		case float64:
+			hit.Rank = r
+		case string:
+			// offsets() lists four numbers per matched term
+			hit.Rank = -float64(len(strings.Fields(r)) / 4)
+		case []byte:
+			hit.Rank = -float64(len(strings.Fields(string(r))) / 4)
+		}
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	if !fts5 {
+		sort.SliceStable(hits, func(i, j int) bool { return hits[i].Rank < hits[j].Rank })
+		if limit > 0 && len(hits) > limit {
+			hits = hits[:limit]
+		}
+	}
+	return hits, nil
+}
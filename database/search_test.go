@@ -0,0 +1,161 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	rows := []RowData{
+		{"Note": "Dinner at the restaurant Marco mentioned", "Project": "Food", "Location": "Rome"},
+		{"Note": "Buy groceries", "Project": "Food", "Location": "Market"},
+		{"Note": "Marco's birthday", "Project": "Friends", "Location": "Marco's place, restaurant upstairs"},
+	}
+	for _, row := range rows {
+		if _, err := db.CreateRow("General", row); err != nil {
+			t.Fatalf("Failed to create test row: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		wantIDs []int
+	}{
+		{name: "single word", query: "groceries", wantIDs: []int{2}},
+		{name: "every word must match", query: "restaurant marco", wantIDs: []int{1, 3}},
+		{name: "prefix", query: "resta", wantIDs: []int{1, 3}},
+		{name: "punctuation is ignored", query: `"marco" -(`, wantIDs: []int{1, 3}},
+		{name: "no match", query: "zebra", wantIDs: nil},
+		{name: "empty", query: "  ", wantIDs: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, err := db.Search(tt.query, 10)
+			if err != nil {
+				t.Fatalf("Search(%q) error = %v", tt.query, err)
+			}
+			got := make(map[int]bool)
+			for _, hit := range hits {
+				if hit.Category != "General" || hit.Snippet == "" {
+					t.Errorf("Search(%q) hit = %+v, want a General hit with a snippet", tt.query, hit)
+				}
+				got[hit.ID] = true
+			}
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("Search(%q) = %+v, want ids %v", tt.query, hits, tt.wantIDs)
+			}
+			for _, id := range tt.wantIDs {
+				if !got[id] {
+					t.Errorf("Search(%q) = %+v, want id %d", tt.query, hits, id)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchRanksBetterMatchesFirst(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	if _, err := db.CreateRow("General", RowData{"Note": "Pasta", "Project": "Marco", "Location": "Home"}); err != nil {
+		t.Fatalf("Failed to create test row: %v", err)
+	}
+	if _, err := db.CreateRow("General", RowData{"Note": "Marco", "Project": "Marco", "Location": "Marco"}); err != nil {
+		t.Fatalf("Failed to create test row: %v", err)
+	}
+
+	hits, err := db.Search("marco", 10)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(hits) != 2 || hits[0].ID != 2 {
+		t.Errorf("Search() = %+v, want row 2 first", hits)
+	}
+	if !strings.Contains(hits[0].Snippet, "[Marco]") {
+		t.Errorf("Search() snippet = %q, want the match highlighted", hits[0].Snippet)
+	}
+}
+
+func TestSearchIndexFollowsChanges(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	if _, err := db.CreateRow("General", RowData{"Note": "Old note", "Project": "A", "Location": "X"}); err != nil {
+		t.Fatalf("Failed to create test row: %v", err)
+	}
+
+	search := func(query string) int {
+		t.Helper()
+		hits, err := db.Search(query, 10)
+		if err != nil {
+			t.Fatalf("Search(%q) error = %v", query, err)
+		}
+		return len(hits)
+	}
+
+	if err := db.UpdateRow("General", 1, RowData{"Note": "Fresh note"}); err != nil {
+		t.Fatalf("UpdateRow() error = %v", err)
+	}
+	if search("old") != 0 || search("fresh") != 1 {
+		t.Error("search index was not updated by UpdateRow")
+	}
+
+	if err := db.RemoveColumn("General", "Note"); err != nil {
+		t.Fatalf("RemoveColumn() error = %v", err)
+	}
+	if search("fresh") != 0 || search("A") != 1 {
+		t.Error("search index was not rebuilt by RemoveColumn")
+	}
+
+	if err := db.DeleteRow("General", 1); err != nil {
+		t.Fatalf("DeleteRow() error = %v", err)
+	}
+	if search("A") != 0 {
+		t.Error("search index still lists a deleted row")
+	}
+}
+
+func TestSearchIndexBackfillsExistingRows(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	if _, err := db.CreateRow("General", RowData{"Note": "Before the index", "Project": "A", "Location": "X"}); err != nil {
+		t.Fatalf("Failed to create test row: %v", err)
+	}
+
+	// Rebuild the index as the migration would on an existing database
+	tx, err := db.DB.Begin()
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if _, err := tx.Exec("DROP TABLE search_index"); err != nil {
+		t.Fatalf("failed to drop search index: %v", err)
+	}
+	if err := createSearchIndex(tx); err != nil {
+		t.Fatalf("createSearchIndex() error = %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	hits, err := db.Search("before", 10)
+	if err != nil || len(hits) != 1 {
+		t.Errorf("Search() = %+v, %v, want the existing row", hits, err)
+	}
+}
+
+func TestCreateCategoryRejectsSearchIndexNames(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	for _, name := range []string{"search_index", "search_index_content"} {
+		if err := db.CreateCategory(CategoryTemplate{Name: name, ColumnsID: []int{1}}); err == nil {
+			t.Errorf("CreateCategory(%q) succeeded, want reserved name error", name)
+		}
+	}
+}
@@ -71,6 +71,13 @@ func SetupDatabase(path string, logger *logging.Logger) (*Database, error) {
 		logger.LogInfo("New database created with default schema.")
 	}
 
+	// Upgrade the schema to the version expected by this binary
+	if err := database.migrate(); err != nil {
+		logger.LogErr("Failed to migrate database: %v", err)
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to migrate database: %v", err)
+	}
+
 	logger.LogInfo("Database connection established correctly")
 	return database, nil
 }
@@ -146,7 +153,7 @@ func (db *Database) createDefaultDB() error {
 // populateDefaultDB fills the database with initial data
 func populateDefaultDB(tx *sql.Tx, logger *logging.Logger) error {
 	// Insert version
-	_, err := tx.Exec("INSERT INTO metadata (version) VALUES (?)", currentVersion)
+	_, err := tx.Exec("INSERT INTO metadata (version) VALUES (?)", baseVersion)
 	if err != nil {
 		logger.LogErr("Failed to insert version: %v", err)
 		return fmt.Errorf("failed to insert version: %v", err)
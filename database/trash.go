@@ -0,0 +1,271 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DeletedRow is a soft-deleted row waiting in the trash
+type DeletedRow struct {
+	Category  string
+	ID        int
+	DeletedAt time.Time
+	Row       RowData
+}
+
+// ListDeleted returns the soft-deleted rows of a category, most recently deleted first.
+// An empty category lists the trash of every category.
+func (db *Database) ListDeleted(categoryName string) ([]DeletedRow, error) {
+	categories := []string{categoryName}
+	if categoryName == "" {
+		tx, err := db.DB.Begin()
+		if err != nil {
+			return nil, fmt.Errorf(failedToBeginTxString, err)
+		}
+		categories, err = categoryTableNames(tx)
+		tx.Rollback()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var deleted []DeletedRow
+	for _, category := range categories {
+		rows, err := db.listDeletedRows(category)
+		if err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, rows...)
+	}
+
+	sort.SliceStable(deleted, func(i, j int) bool {
+		if !deleted[i].DeletedAt.Equal(deleted[j].DeletedAt) {
+			return deleted[i].DeletedAt.After(deleted[j].DeletedAt)
+		}
+		return deleted[i].ID > deleted[j].ID
+	})
+	return deleted, nil
+}
+
+// listDeletedRows returns the soft-deleted rows of a single category
+func (db *Database) listDeletedRows(categoryName string) ([]DeletedRow, error) {
+	table, err := quoteCategory(categoryName)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT * FROM %s WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC", table)
+	rows, err := db.DB.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deleted rows: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf(columnsFetchErrorString, err)
+	}
+
+	var result []RowData
+	for rows.Next() {
+		rowData, err := scanRow(rows, columns)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, rowData)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	rows.Close()
+
+	types, err := getColumnTypes(db.DB, categoryName)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make([]DeletedRow, len(result))
+	for i, rowData := range result {
+		db.decodeRow(rowData, types)
+		deleted[i] = DeletedRow{Category: categoryName, Row: rowData}
+		if id, ok := rowData["id"].(int); ok {
+			deleted[i].ID = id
+		}
+		if deletedAt, ok := rowData["deleted_at"].(time.Time); ok {
+			deleted[i].DeletedAt = deletedAt
+		}
+	}
+	return deleted, nil
+}
+
+// RestoreRow takes a soft-deleted row out of the trash.
+// Items that are opened but not closed are tracked as pending again.
+func (db *Database) RestoreRow(categoryName string, id int) error {
+	table, err := quoteCategory(categoryName)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf(failedToBeginTxString, err)
+	}
+	defer tx.Rollback()
+
+	old, err := rowSnapshot(tx, categoryName, id)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(
+		"UPDATE %s SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NOT NULL",
+		table,
+	)
+	result, err := tx.Exec(query, id)
+	if err != nil {
+		return fmt.Errorf("failed to restore row: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf(affectedRowsErrorString, err)
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	open, err := isOpenItem(tx, categoryName, id)
+	if err != nil {
+		return err
+	}
+	if open {
+		if err := db.addToPending(tx, categoryName, id); err != nil {
+			return err
+		}
+	}
+
+	if err := indexRow(tx, categoryName, id); err != nil {
+		return err
+	}
+
+	if err := recordChange(tx, HistoryRestore, categoryName, id, old); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// openItemCondition returns the SQL condition matching open rows of a category.
+// It reports false when the category has no Opened column and never has open items.
+func openItemCondition(columns []tableColumn) (string, bool) {
+	if findColumn(columns, "Opened") == -1 {
+		return "", false
+	}
+
+	opened, closed := quoteIdentifier("Opened"), quoteIdentifier("Closed")
+	condition := fmt.Sprintf("%s IS NOT NULL AND %s != ''", opened, opened)
+	if findColumn(columns, "Closed") != -1 {
+		condition += fmt.Sprintf(" AND (%s IS NULL OR %s = '')", closed, closed)
+	}
+	return condition, true
+}
+
+// isOpenItem reports whether a row has been opened and not closed yet.
+// Rows of categories without an Opened column are never open items.
+func isOpenItem(tx *sql.Tx, categoryName string, id int) (bool, error) {
+	columns, err := getTableColumns(tx, categoryName)
+	if err != nil {
+		return false, err
+	}
+	condition, ok := openItemCondition(columns)
+	if !ok {
+		return false, nil
+	}
+
+	table, err := quoteCategory(categoryName)
+	if err != nil {
+		return false, err
+	}
+
+	var count int
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE id = ? AND %s", table, condition)
+	if err := tx.QueryRow(query, id).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check whether item %d is open: %w", id, err)
+	}
+	return count > 0, nil
+}
+
+// syncPending tracks a row as pending exactly when it is an open item
+func (db *Database) syncPending(tx *sql.Tx, categoryName string, id int) error {
+	open, err := isOpenItem(tx, categoryName, id)
+	if err != nil {
+		return err
+	}
+	if open {
+		return db.addToPending(tx, categoryName, id)
+	}
+	return db.removeFromPending(tx, categoryName, id)
+}
+
+// PurgeDeleted permanently removes the rows of every category that were deleted before olderThan.
+// It returns the number of rows removed.
+func (db *Database) PurgeDeleted(olderThan time.Time) (int, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return 0, fmt.Errorf(failedToBeginTxString, err)
+	}
+	defer tx.Rollback()
+
+	categories, err := categoryTableNames(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	// deleted_at is written by SQLite in UTC
+	cutoff := olderThan.UTC().Format(datetimeFormat)
+
+	purged := 0
+	for _, category := range categories {
+		table, err := quoteCategory(category)
+		if err != nil {
+			return 0, err
+		}
+
+		ids, err := queryIDs(tx, fmt.Sprintf("SELECT id FROM %s WHERE deleted_at IS NOT NULL AND deleted_at < ?", table), cutoff)
+		if err != nil {
+			return 0, fmt.Errorf("failed to query deleted rows of %s: %w", category, err)
+		}
+
+		for _, id := range ids {
+			if err := recordPurge(tx, category, id); err != nil {
+				return 0, err
+			}
+			if _, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE id = ?", table), id); err != nil {
+				return 0, fmt.Errorf("failed to purge row %d of %s: %w", id, category, err)
+			}
+			if err := db.deleteFromPending(tx, category, id); err != nil {
+				return 0, err
+			}
+			if err := removeItemLinks(tx, ItemRef{Category: category, ID: id}); err != nil {
+				return 0, err
+			}
+		}
+		purged += len(ids)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return purged, nil
+}
+
+// recordPurge records that a row is about to be removed for good, keeping its last values
+func recordPurge(tx *sql.Tx, categoryName string, id int) error {
+	old, err := rowSnapshot(tx, categoryName, id)
+	if err != nil {
+		return err
+	}
+	return recordHistory(tx, HistoryPurge, categoryName, id, old, nil)
+}
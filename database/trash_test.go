@@ -0,0 +1,164 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+// setupChoresCategory creates a Chores category tracking Opened and Closed items
+func setupChoresCategory(t *testing.T, db *TestDB) {
+	t.Helper()
+
+	datatypes := []Datatype{
+		{Name: "Opened", VariableType: TimeType, CompletionValue: LastCompletion, CompletionSort: LastSort, ValueCheck: nonemptyCheck, FillBehavior: Open},
+		{Name: "Closed", VariableType: TimeType, CompletionValue: DateCompletion, CompletionSort: LastSort, ValueCheck: nonemptyCheck, FillBehavior: Close},
+	}
+	createTestCategory(t, db, "Chores", datatypes, 1) // Note
+}
+
+// pendingPointers returns the pending pointers as a set
+func pendingPointers(t *testing.T, db *TestDB) map[string]bool {
+	t.Helper()
+	pointers, err := db.GetPendingPointers()
+	if err != nil {
+		t.Fatalf("GetPendingPointers() error = %v", err)
+	}
+	set := make(map[string]bool)
+	for _, pointer := range pointers {
+		set[pointer] = true
+	}
+	return set
+}
+
+func TestListDeletedAndRestoreRow(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	setupChoresCategory(t, db)
+
+	if _, err := db.CreateRow("Chores", RowData{"Opened": "2024-05-01 09:00", "Note": "Fix the bike"}); err != nil {
+		t.Fatalf("Failed to create test row: %v", err)
+	}
+	if _, err := db.CreateRow("General", RowData{"Note": "Old idea", "Project": "A", "Location": "X"}); err != nil {
+		t.Fatalf("Failed to create test row: %v", err)
+	}
+	for _, item := range []struct {
+		category string
+		id       int
+	}{{"Chores", 1}, {"General", 1}} {
+		if err := db.DeleteRow(item.category, item.id); err != nil {
+			t.Fatalf("DeleteRow() error = %v", err)
+		}
+	}
+
+	if pendingPointers(t, db)["Chores:1"] {
+		t.Error("DeleteRow() left the deleted item pending")
+	}
+
+	deleted, err := db.ListDeleted("")
+	if err != nil {
+		t.Fatalf("ListDeleted() error = %v", err)
+	}
+	if len(deleted) != 2 {
+		t.Fatalf("ListDeleted() = %+v, want 2 rows", deleted)
+	}
+	for _, item := range deleted {
+		if item.DeletedAt.IsZero() || item.Row["Note"] == nil {
+			t.Errorf("ListDeleted() item = %+v, want a deletion time and the row", item)
+		}
+	}
+
+	deleted, err = db.ListDeleted("General")
+	if err != nil || len(deleted) != 1 || deleted[0].Category != "General" {
+		t.Fatalf("ListDeleted(General) = %+v, %v, want the General row", deleted, err)
+	}
+
+	if err := db.RestoreRow("Chores", 1); err != nil {
+		t.Fatalf("RestoreRow() error = %v", err)
+	}
+	if _, err := db.ReadRow("Chores", 1); err != nil {
+		t.Errorf("ReadRow() after restore error = %v", err)
+	}
+	if !pendingPointers(t, db)["Chores:1"] {
+		t.Error("RestoreRow() did not track the open item as pending")
+	}
+
+	if err := db.RestoreRow("Chores", 1); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("RestoreRow() of a live row error = %v, want sql.ErrNoRows", err)
+	}
+	if err := db.RestoreRow("General", 1); err != nil {
+		t.Errorf("RestoreRow() error = %v", err)
+	}
+	if pendingPointers(t, db)["General:1"] {
+		t.Error("RestoreRow() tracked a row without Opened as pending")
+	}
+}
+
+func TestRestoreRowKeepsClosedItemsClosed(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+	setupChoresCategory(t, db)
+
+	if _, err := db.CreateRow("Chores", RowData{"Opened": "2024-05-01 09:00", "Note": "Water plants"}); err != nil {
+		t.Fatalf("Failed to create test row: %v", err)
+	}
+	if _, err := db.CloseItem("Chores", 1, "2024-05-01 10:00:00"); err != nil {
+		t.Fatalf("CloseItem() error = %v", err)
+	}
+	if err := db.DeleteRow("Chores", 1); err != nil {
+		t.Fatalf("DeleteRow() error = %v", err)
+	}
+	if err := db.RestoreRow("Chores", 1); err != nil {
+		t.Fatalf("RestoreRow() error = %v", err)
+	}
+	if pendingPointers(t, db)["Chores:1"] {
+		t.Error("RestoreRow() tracked a closed item as pending")
+	}
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	for _, note := range []string{"Old", "Recent", "Live"} {
+		if _, err := db.CreateRow("General", RowData{"Note": note, "Project": "A", "Location": "X"}); err != nil {
+			t.Fatalf("Failed to create test row: %v", err)
+		}
+	}
+	for _, id := range []int{1, 2} {
+		if err := db.DeleteRow("General", id); err != nil {
+			t.Fatalf("DeleteRow() error = %v", err)
+		}
+	}
+	old := time.Now().UTC().Add(-60 * 24 * time.Hour).Format(datetimeFormat)
+	if _, err := db.DB.Exec("UPDATE General SET deleted_at = ? WHERE id = 1", old); err != nil {
+		t.Fatalf("failed to age deleted row: %v", err)
+	}
+
+	purged, err := db.PurgeDeleted(time.Now().Add(-30 * 24 * time.Hour))
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeDeleted() = %d, %v, want 1 row purged", purged, err)
+	}
+
+	deleted, err := db.ListDeleted("General")
+	if err != nil || len(deleted) != 1 || deleted[0].ID != 2 {
+		t.Errorf("ListDeleted() = %+v, %v, want only the recent row", deleted, err)
+	}
+	if err := db.RestoreRow("General", 1); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("RestoreRow() of a purged row error = %v, want sql.ErrNoRows", err)
+	}
+
+	// The purge is the last entry of the row's history, with its final values
+	entries, err := db.RowHistory("General", 1)
+	if err != nil {
+		t.Fatalf("RowHistory() error = %v", err)
+	}
+	last := entries[len(entries)-1]
+	if last.Operation != HistoryPurge || last.OldValues["Note"] != "Old" || last.NewValues != nil {
+		t.Errorf("last history entry = %+v, want the purge of the old row", last)
+	}
+	if err := db.RevertRow("General", 1, last.ID); err == nil {
+		t.Errorf("RevertRow() of a purge succeeded")
+	}
+}
@@ -18,7 +18,7 @@ type Metadata struct {
 	ID        int
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Version   int
+	Version   string
 }
 
 // Datatype struct holds the columns information.
@@ -32,28 +32,58 @@ type Datatype struct {
 	FillBehavior    string
 }
 
-// Category struct holds the category information.
+// DatatypeOverride holds the category specific settings of a datatype.
+// Empty fields are inherited from the global datatype.
+type DatatypeOverride struct {
+	Category        string
+	Datatype        string
+	ValueCheck      string
+	CompletionValue string
+	CompletionSort  string
+	FillBehavior    string
+}
+
+// Category struct holds the category information stored in the categories registry.
 type Category struct {
-	ID        int
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	DeletedAt sql.NullTime
-	Datatypes RowData
+	ID          int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   sql.NullTime // set when the category is archived
+	Name        string       // name of the category table
+	DisplayName string
+	Description string
+	Icon        string
+	Colour      string
 }
 
-// Pending struct holds the pointer of unclosed rows.
+// Pending struct holds the reference to an unclosed row.
 type Pending struct {
 	ID        int
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt sql.NullTime
-	Pointer   string // Format: "Category:ID", e.g., "General:123"
+	Category  string
+	ItemID    int
+}
+
+// PendingItem is an open row resolved from its pending reference.
+type PendingItem struct {
+	Category string
+	ID       int
+	Opened   time.Time
+	Elapsed  time.Duration
+	Preview  string // Note and Project values, if the category has them
 }
 
 type CategoryTemplate struct {
 	Name string
 	// contains a list of numerical IDs for the rows of the datatypes
 	ColumnsID []int
+	// optional details stored in the categories registry
+	DisplayName string
+	Description string
+	Icon        string
+	Colour      string
 }
 
 type RowData map[string]interface{}
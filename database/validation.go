@@ -2,13 +2,15 @@ package database
 
 import (
 	"Attimo/logging"
+	"Attimo/timeparse"
 	"database/sql"
+	"errors"
 	"fmt"
 	"net/mail"
 	"net/url"
 	"os"
 	"regexp"
-	"strconv"
+	"sort"
 	"strings"
 	"time"
 )
@@ -17,101 +19,134 @@ const (
 	TypeMismatch = " %v is not %s"
 )
 
-// SplitStringArgument splits the type into the type and a list of string parameters
-// "IN(1,2,3,4)" -> "IN", ["1", "2", "3", "4"]
-func SplitStringArgument(input string) (string, []string) {
-	openParenIndex := strings.Index(input, "(")
-	if openParenIndex == -1 {
-		return input, nil
+// ValidationError describes why the value of a single field was rejected
+type ValidationError struct {
+	Field   string      // column name
+	Rule    string      // check that failed, e.g. "range", or "type" when the value could not be parsed
+	Args    []string    // arguments of the check, e.g. ["1", "10"]
+	Value   interface{} // offending value
+	Message string      // human readable explanation
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid value for column %s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors collects the validation errors of every field of a row
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
 	}
+	return strings.Join(messages, "; ")
+}
 
-	typeName := strings.TrimSpace(input[:openParenIndex])
-	paramsString := input[openParenIndex+1 : len(input)-1]
-	params := strings.Split(paramsString, ",")
+// typeRule is the rule reported when a value cannot be parsed into the datatype
+const typeRule = "type"
 
-	for i, param := range params {
-		params[i] = strings.TrimSpace(param)
+// ColumnRule is the rule reported when a field is not a column of the category
+const ColumnRule = "column"
+
+// Validate checks a value against the datatype's check rule.
+// It returns nil when the value is valid.
+// A ValueCheck that cannot be parsed rejects every value, with the check itself as the rule.
+func (dt *Datatype) Validate(value interface{}) *ValidationError {
+	check, err := dt.compiledCheck()
+	if err != nil {
+		return &ValidationError{
+			Field:   dt.Name,
+			Rule:    dt.ValueCheck,
+			Value:   value,
+			Message: fmt.Sprintf("has an invalid check: %v", err),
+		}
 	}
 
-	return typeName, params
+	verr := check.eval(value)
+	if verr != nil {
+		verr.Field = dt.Name
+	}
+	return verr
 }
 
 // TODO REMOVE LOGGING WHEN OPERATIONS BECOME MORE FREQUENT
 // ValidateCheck performs validation based on the datatype's check rules
 func (dt *Datatype) ValidateCheck(value interface{}, logger *logging.Logger) bool {
-	typeS, args := SplitStringArgument(dt.ValueCheck)
-
-	switch typeS {
-	case nonemptyCheck:
-		valid := validateNonempty(value)
-		if !valid {
-			logger.LogWarn("Nonempty validation failed for value: %v", value)
-		}
-		return valid
-	case RangeCheck:
-		valid := validateInRange(value, args)
-		if !valid {
-			logger.LogWarn("Range validation failed for value: %v (args: %v)", value, args)
-		}
-		return valid
-	case SetCheck:
-		valid := validateInSet(value, args)
-		if !valid {
-			logger.LogWarn("Set validation failed for value: %v (args: %v)", value, args)
-		}
-		return valid
-	case NoCheck:
+	verr := dt.Validate(value)
+	if verr == nil {
 		return true
-	case URLCheck:
-		valid := validateURL(value)
-		if !valid {
-			logger.LogWarn("URL validation failed for value: %v", value)
-		}
-		return valid
-	case MailCheck:
-		valid := validateEmail(value)
-		if !valid {
-			logger.LogWarn("Email validation failed for value: %v", value)
-		}
-		return valid
-	case PhoneCheck:
-		valid := validatePhone(value)
-		if !valid {
-			logger.LogWarn("Phone validation failed for value: %v", value)
-		}
-		return valid
-	case FileCheck:
-		valid := validateFileExists(value)
-		if !valid {
-			logger.LogWarn("File validation failed for value: %v", value)
+	}
+
+	if !knownChecks[verr.Rule] {
+		logger.LogErr("Unrecognized type: %v", dt.ValueCheck)
+	} else {
+		logger.LogWarn("%s validation failed for value: %v (args: %v)", verr.Rule, value, verr.Args)
+	}
+	return false
+}
+
+// ValidateInput coerces a value to the datatype and validates it.
+// Text that must be a date is converted to the storage layout.
+// Returns the coerced value, or a ValidationError describing the problem.
+func (dt *Datatype) ValidateInput(value interface{}) (interface{}, *ValidationError) {
+	coerced, err := CoerceValue(dt.VariableType, value)
+	if err != nil {
+		return nil, &ValidationError{
+			Field:   dt.Name,
+			Rule:    typeRule,
+			Args:    []string{dt.VariableType},
+			Value:   value,
+			Message: err.Error(),
 		}
-		return valid
-	case DateCheck:
-		valid := validateDate(value)
-		if !valid {
-			logger.LogWarn("Date validation failed for value: %v", value)
+	}
+
+	if verr := dt.Validate(coerced); verr != nil {
+		return nil, verr
+	}
+
+	// Text checked as a date stores the time it names, "tomorrow" must not be kept as typed
+	if str, ok := coerced.(string); ok {
+		if check, err := dt.compiledCheck(); err == nil && check.requiresDate() {
+			if t, err := timeparse.Parse(str, time.Now()); err == nil {
+				coerced = timeparse.Format(t)
+			}
 		}
-		return valid
-	default:
-		logger.LogErr("Unrecognized type: %v", dt.ValueCheck)
-		return false
 	}
+	return coerced, nil
 }
 
 // Individual validation functions
 func validateNonempty(value interface{}) bool {
-	str, ok := value.(string)
-	if !ok {
+	switch v := value.(type) {
+	case nil:
 		return false
+	case string:
+		return v != ""
+	case []string:
+		return len(v) > 0
+	case time.Time:
+		return !v.IsZero()
+	default:
+		return true
 	}
-	return str != ""
 }
 
 func validateInSet(value interface{}, args []string) bool {
-	str, ok := value.(string)
-	if !ok {
+	// every item of a list must belong to the set
+	if items, ok := value.([]string); ok {
+		for _, item := range items {
+			if !validateInSet(item, args) {
+				return false
+			}
+		}
+		return len(items) > 0
+	}
+
+	if value == nil {
 		return false
 	}
+	str := fmt.Sprint(value)
 	for _, arg := range args {
 		if str == arg {
 			return true
@@ -129,23 +164,37 @@ func validateURL(value interface{}) bool {
 	return err == nil && u.Scheme != "" && u.Host != ""
 }
 
-func validateInRange(value interface{}, args []string) bool {
-	if args == nil || len(args) != 2 {
+func validateInRange(value interface{}, min, max float64) bool {
+	var f float64
+	switch v := value.(type) {
+	case int:
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	case float64:
+		f = v
+	default:
 		return false
 	}
-	i, ok := value.(int)
-	if !ok {
-		return false
+	return f >= min && f <= max
+}
+
+func validateRegex(value interface{}, re *regexp.Regexp) bool {
+	if items, ok := value.([]string); ok {
+		for _, item := range items {
+			if !re.MatchString(item) {
+				return false
+			}
+		}
+		return len(items) > 0
 	}
-	min, err := strconv.Atoi(args[0])
-	if err != nil {
+	if value == nil {
 		return false
 	}
-	max, err := strconv.Atoi(args[1])
-	if err != nil {
-		return false
+	if t, ok := value.(time.Time); ok {
+		return re.MatchString(t.Format(datetimeFormat))
 	}
-	return i >= min && i <= max
+	return re.MatchString(fmt.Sprint(value))
 }
 
 func validateEmail(value interface{}) bool {
@@ -176,11 +225,14 @@ func validateFileExists(value interface{}) bool {
 }
 
 func validateDate(value interface{}) bool {
+	if t, ok := value.(time.Time); ok {
+		return !t.IsZero()
+	}
 	date, ok := value.(string)
 	if !ok {
 		return false
 	}
-	_, err := time.Parse(dateFormat, date)
+	_, err := timeparse.Parse(date, time.Now())
 	return err == nil
 }
 
@@ -207,28 +259,33 @@ func GetDatatypeByName(tx *sql.Tx, name string) (*Datatype, error) {
 	return &dt, nil
 }
 
-// validateField validates a single field value against its datatype
-func (d *Database) validateField(tx *sql.Tx, columnName string, validateEmpty bool, value interface{}) error {
-	datatype, err := GetDatatypeByName(tx, columnName)
+// validateField coerces a single field value to its datatype, as overridden by the category,
+// and validates it. The coerced value is returned, or a *ValidationError for invalid values.
+func (d *Database) validateField(tx *sql.Tx, categoryName, columnName string, validateEmpty bool, value interface{}) (interface{}, error) {
+	datatype, err := GetCategoryDatatype(tx, categoryName, columnName)
 	if err != nil {
-		return fmt.Errorf("failed to get datatype for column %s: %w", columnName, err)
+		return nil, fmt.Errorf("failed to get datatype for column %s: %w", columnName, err)
 	}
 
 	if !validateEmpty && isEmptyString(value) {
-		return nil
+		return value, nil
 	}
 
-	if !datatype.ValidateCheck(value, d.logger) {
-		return fmt.Errorf("invalid value for column %s: %v", columnName, value)
+	coerced, verr := datatype.ValidateInput(value)
+	if verr != nil {
+		verr.Field = columnName
+		d.logger.LogWarn("Validation failed: %v", verr)
+		return nil, verr
 	}
 
-	return nil
+	return coerced, nil
 }
 
 // validateInputData validates all fields in the input data
 // against the column names and datatypes of the category
 // validateEmpty determines whether empty fields are allowed, true if they are allowed
-func (d *Database) validateInputData(tx *sql.Tx, categoryName string, data RowData, validateEmpty bool) error {
+// Returns a copy of the data with every value coerced to its datatype
+func (d *Database) validateInputData(tx *sql.Tx, categoryName string, data RowData, validateEmpty bool) (RowData, error) {
 	// Get column information
 	rows, err := tx.Query(`
 		SELECT name 
@@ -239,7 +296,7 @@ func (d *Database) validateInputData(tx *sql.Tx, categoryName string, data RowDa
 		  AND name != 'deleted_at'
 	`, categoryName)
 	if err != nil {
-		return fmt.Errorf("failed to get column info: %w", err)
+		return nil, fmt.Errorf("failed to get column info: %w", err)
 	}
 	defer rows.Close()
 
@@ -248,21 +305,49 @@ func (d *Database) validateInputData(tx *sql.Tx, categoryName string, data RowDa
 	for rows.Next() {
 		var colName string
 		if err := rows.Scan(&colName); err != nil {
-			return fmt.Errorf("error scanning column name: %w", err)
+			return nil, fmt.Errorf("error scanning column name: %w", err)
 		}
 		validColumns[colName] = true
 	}
 
-	// Validate each provided field
-	for field, value := range data {
+	rows.Close()
+
+	// Validate each provided field, in a stable order
+	fields := make([]string, 0, len(data))
+	for field := range data {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	coerced := make(RowData, len(data))
+	var verrs ValidationErrors
+	for _, field := range fields {
+		value := data[field]
 		if !validColumns[field] {
-			return fmt.Errorf("invalid field name: %s", field)
+			verrs = append(verrs, &ValidationError{
+				Field:   field,
+				Rule:    ColumnRule,
+				Value:   value,
+				Message: fmt.Sprintf("is not a column of category %s", categoryName),
+			})
+			continue
 		}
 
-		if err := d.validateField(tx, field, validateEmpty, value); err != nil {
-			return err
+		value, err := d.validateField(tx, categoryName, field, validateEmpty, value)
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			verrs = append(verrs, verr)
+			continue
 		}
+		if err != nil {
+			return nil, err
+		}
+		coerced[field] = value
+	}
+
+	if len(verrs) > 0 {
+		return nil, verrs
 	}
 
-	return nil
+	return coerced, nil
 }
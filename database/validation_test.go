@@ -0,0 +1,123 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDatatypeValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		check    string
+		value    interface{}
+		wantRule string
+	}{
+		{name: "valid", check: nonemptyCheck, value: "x", wantRule: ""},
+		{name: "empty", check: nonemptyCheck, value: "", wantRule: nonemptyCheck},
+		{name: "out of range", check: RangeCheck + "(1,5)", value: 9, wantRule: RangeCheck},
+		{name: "not in set", check: SetCheck + "(Low,High)", value: "Medium", wantRule: SetCheck},
+		{name: "unknown check", check: "positive", value: 1, wantRule: "positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dt := Datatype{Name: "Field", ValueCheck: tt.check}
+			verr := dt.Validate(tt.value)
+			if tt.wantRule == "" {
+				if verr != nil {
+					t.Errorf("Validate() = %v, want nil", verr)
+				}
+				return
+			}
+			if verr == nil {
+				t.Fatalf("Validate() = nil, want a %s error", tt.wantRule)
+			}
+			if verr.Rule != tt.wantRule || verr.Field != "Field" || verr.Value != tt.value || verr.Message == "" {
+				t.Errorf("Validate() = %+v, want rule %s with field, value and message", verr, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestValidateInputStoresDates(t *testing.T) {
+	tests := []struct {
+		name  string
+		check string
+		value string
+		want  string
+	}{
+		{name: "date", check: DateCheck, value: "2024-05-01 18:30", want: "2024-05-01 18:30:00"},
+		{name: "inside and", check: AndCheck + "(nonempty,date)", value: "01-05-2024", want: "2024-05-01 00:00:00"},
+		{name: "or accepts other text", check: OrCheck + "(date,in(soon))", value: "soon", want: "soon"},
+		{name: "no date check", check: nonemptyCheck, value: "2024-05-01", want: "2024-05-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dt := Datatype{Name: "Field", VariableType: StringType, ValueCheck: tt.check}
+			got, verr := dt.ValidateInput(tt.value)
+			if verr != nil {
+				t.Fatalf("ValidateInput() error = %v", verr)
+			}
+			if got != tt.want {
+				t.Errorf("ValidateInput() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// A phrase is stored as the time it names
+	dt := Datatype{Name: "Field", VariableType: StringType, ValueCheck: DateCheck}
+	got, verr := dt.ValidateInput("tomorrow 9am")
+	if verr != nil {
+		t.Fatalf("ValidateInput() error = %v", verr)
+	}
+	str, _ := got.(string)
+	if _, err := time.ParseInLocation(datetimeFormat, str, time.Local); err != nil {
+		t.Errorf("ValidateInput() = %v, want a time in the storage layout", got)
+	}
+}
+
+func TestValidationErrorsCollectAllFields(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.tearDown(t)
+
+	rating := Datatype{Name: "Rating", VariableType: IntType, CompletionValue: NoCompletion, CompletionSort: NoSort, ValueCheck: RangeCheck + "(1,5)", FillBehavior: Open}
+	if _, err := db.CreateDatatype(rating); err != nil {
+		t.Fatalf("Failed to create test datatype: %v", err)
+	}
+	if err := db.AddColumn("General", "Rating"); err != nil {
+		t.Fatalf("Failed to add test column: %v", err)
+	}
+
+	err := db.UpdateRow("General", 1, RowData{
+		"Note":    "",
+		"Rating":  "9",
+		"Unknown": "x",
+		"Project": "fine",
+	})
+
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) {
+		t.Fatalf("UpdateRow() error = %v, want ValidationErrors", err)
+	}
+
+	want := map[string]string{
+		"Note":    nonemptyCheck,
+		"Rating":  RangeCheck,
+		"Unknown": ColumnRule,
+	}
+	if len(verrs) != len(want) {
+		t.Fatalf("UpdateRow() returned %d validation errors, want %d: %v", len(verrs), len(want), verrs)
+	}
+	for _, verr := range verrs {
+		if want[verr.Field] != verr.Rule {
+			t.Errorf("validation error %+v, want rule %s", verr, want[verr.Field])
+		}
+	}
+
+	_, err = db.CreateRow("General", RowData{"Note": "x", "Rating": "many"})
+	if !errors.As(err, &verrs) || len(verrs) != 1 || verrs[0].Rule != typeRule {
+		t.Errorf("CreateRow() error = %v, want a single type error", err)
+	}
+}
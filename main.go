@@ -1,7 +1,9 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"os"
 	"path/filepath"
 
 	ctrl "Attimo/control"
@@ -10,34 +12,120 @@ import (
 	view "Attimo/tui"
 )
 
-func main() {
+// dbPathEnv names the environment variable holding the default database path
+const dbPathEnv = "ATTIMO_DB"
 
-	dbFolder := filepath.Join(".", "db")
-	dbPath := filepath.Join(dbFolder, "attimo.db")
+// defaultDBPath returns $ATTIMO_DB, or db/attimo.db in the working directory
+func defaultDBPath() string {
+	if path := os.Getenv(dbPathEnv); path != "" {
+		return path
+	}
+	return filepath.Join(".", "db", "attimo.db")
+}
+
+// fail reports a setup error and exits with a non-zero status
+func fail(logger *log.Logger, format string, v ...interface{}) {
+	if logger != nil {
+		logger.LogErr(format, v...)
+	}
+	fmt.Fprintf(os.Stderr, format+"\n", v...)
+	os.Exit(1)
+}
+
+func main() {
+	dbPath := flag.String("db", defaultDBPath(), "path of the database file, defaults to $"+dbPathEnv+" or ./db/attimo.db")
+	flag.Parse()
 
 	// view.GetLogger()
 	logger, err := log.GetTestLogger()
 	if err != nil {
-		fmt.Println("Could not create logger", err)
-		return
+		fail(nil, "Could not create logger %v", err)
 	}
 
-	view, err := view.New(logger, nil)
+	data, err := data.SetupDatabase(*dbPath, logger)
 	if err != nil {
-		logger.LogErr("Could not create view %v", err)
-		return
+		fail(logger, "Could not create database %v", err)
 	}
 
-	data, err := data.SetupDatabase(dbPath, logger)
+	control, err := ctrl.New(data, logger)
 	if err != nil {
-		logger.LogErr("Could not create database %v", err)
+		fail(logger, "Could not create controller %v", err)
+	}
+
+	// Subcommands run without the interface
+	if args := flag.Args(); len(args) > 0 {
+		if err := runCommand(logger, control, args[0], args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 		return
 	}
 
-	control, err := ctrl.New(data, logger)
+	view, err := view.New(logger, nil)
 	if err != nil {
-		logger.LogErr("Could not create controller %v", err)
-		return
+		fail(logger, "Could not create view %v", err)
 	}
 	view.Init(control)
 }
+
+// runCommand runs a command line subcommand
+func runCommand(logger *log.Logger, control *ctrl.Controller, command string, args []string) error {
+	switch command {
+	case "doctor":
+		return runDoctor(logger, control, args)
+	case "remind":
+		return runRemind(logger, control, args)
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+// runDoctor prints the consistency report, fixing the problems with --fix
+func runDoctor(logger *log.Logger, control *ctrl.Controller, args []string) error {
+	flags := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fix := flags.Bool("fix", false, "repair the problems that can be fixed automatically")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := control.Doctor(logger, *fix)
+	if err != nil {
+		return err
+	}
+
+	if len(report.Problems) == 0 {
+		fmt.Println("No problems found")
+		return nil
+	}
+
+	for _, problem := range report.Problems {
+		status := ""
+		if problem.Fixed {
+			status = " (fixed)"
+		}
+		item := problem.Category
+		if problem.ID != 0 {
+			item = fmt.Sprintf("%s #%d", problem.Category, problem.ID)
+		}
+		fmt.Printf("%s: %s: %s%s\n", problem.Kind, item, problem.Detail, status)
+	}
+
+	fmt.Printf("%d problems found, %d fixed\n", len(report.Problems), report.Fixed())
+	if !*fix && report.Fixed() < len(report.Problems) {
+		fmt.Println("Run with --fix to repair them")
+	}
+	return nil
+}
+
+// runRemind prints the overdue and upcoming items, or passes each one to a hook command
+func runRemind(logger *log.Logger, control *ctrl.Controller, args []string) error {
+	flags := flag.NewFlagSet("remind", flag.ContinueOnError)
+	window := flags.Duration("window", ctrl.DefaultReminderWindow, "how far ahead to look for deadlines")
+	hook := flags.String("hook", os.Getenv(ctrl.ReminderHookEnv), "shell command run for every due item, defaults to $"+ctrl.ReminderHookEnv)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	_, err := control.Remind(logger, *window, *hook, os.Stdout)
+	return err
+}
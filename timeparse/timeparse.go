@@ -0,0 +1,308 @@
+// Package timeparse reads the dates and times typed by users, such as "yesterday 18:30",
+// "+2h15m", "mon 9am", "last friday" or "2024-05-01", and formats them for storage.
+package timeparse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layout is the canonical storage format of times, in local wall clock time
+const Layout = "2006-01-02 15:04:05"
+
+// ShortLayout shows times to the minute, it is also accepted as input
+const ShortLayout = "2006-01-02 15:04"
+
+// localLayouts are the exact formats accepted, read in local time
+var localLayouts = []string{
+	Layout,
+	ShortLayout,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"02-01-2006 15:04",
+}
+
+// zonedLayouts are ISO 8601 formats with an offset, converted to local time
+var zonedLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+}
+
+// dateLayouts are the formats of a day without a time
+var dateLayouts = []string{
+	"2006-01-02",
+	"02-01-2006",
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// unitNames maps the words of "in 3 days" and "2 hours ago" to offset units
+var unitNames = map[string]string{
+	"second": "s", "seconds": "s", "sec": "s", "secs": "s",
+	"minute": "m", "minutes": "m", "min": "m", "mins": "m",
+	"hour": "h", "hours": "h",
+	"day": "d", "days": "d",
+	"week": "w", "weeks": "w",
+}
+
+var (
+	offsetPattern = regexp.MustCompile(`^(?:\d+[wdhms])+$`)
+	offsetPart    = regexp.MustCompile(`(\d+)([wdhms])`)
+	clockPattern  = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(?::(\d{2}))?(am|pm)?$`)
+)
+
+// Weekday returns the weekday named by a full or abbreviated English name, ignoring case
+func Weekday(name string) (time.Weekday, bool) {
+	day, ok := weekdayNames[strings.ToLower(strings.TrimSpace(name))]
+	return day, ok
+}
+
+// Format renders t in the storage layout
+func Format(t time.Time) string {
+	return t.In(time.Local).Format(Layout)
+}
+
+// Parse reads a time relative to now. It accepts:
+//
+//	now, today, tomorrow, yesterday
+//	+90, -15           minutes from now
+//	+2h15m, -1d        offsets in w, d, h, m and s
+//	in 3 days, 2 hours ago
+//	mon 9am, next friday, last fri 18:30, tomorrow at noon
+//	2024-05-01, 01-05-2006, 2024-05-01 9:30pm
+//	2024-05-01 18:30:00 and ISO 8601 with or without an offset
+//
+// A bare weekday is the next one, today included; a day without a time is midnight.
+func Parse(input string, now time.Time) (time.Time, error) {
+	raw := strings.TrimSpace(input)
+	if raw == "" {
+		return time.Time{}, fmt.Errorf("empty time")
+	}
+	now = now.In(time.Local)
+
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, raw, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range zonedLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t.In(time.Local), nil
+		}
+	}
+
+	text := strings.ToLower(strings.Join(strings.Fields(raw), " "))
+	if text == "now" {
+		return now, nil
+	}
+	if text[0] == '+' || text[0] == '-' {
+		return parseOffset(text, now)
+	}
+	if t, ok, err := parseRelative(text, now); ok {
+		return t, err
+	}
+	if t, ok := parseDayAndClock(strings.Fields(text), now); ok {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", raw)
+}
+
+// parseOffset reads "+90" minutes or "+2h15m", "-1d" offsets from now
+func parseOffset(text string, now time.Time) (time.Time, error) {
+	sign := 1
+	if text[0] == '-' {
+		sign = -1
+	}
+	offset := strings.ReplaceAll(text[1:], " ", "")
+
+	// A bare number counts minutes
+	if minutes, err := strconv.Atoi(offset); err == nil {
+		return now.Add(time.Duration(sign*minutes) * time.Minute), nil
+	}
+	if !offsetPattern.MatchString(offset) {
+		return time.Time{}, fmt.Errorf("invalid offset %q, expected minutes or a duration like 2h15m", text)
+	}
+	return applyOffset(now, sign, offset)
+}
+
+// applyOffset moves t by an offset such as "1w2d3h"; days and weeks keep the wall clock time
+func applyOffset(t time.Time, sign int, offset string) (time.Time, error) {
+	for _, part := range offsetPart.FindAllStringSubmatch(offset, -1) {
+		n, err := strconv.Atoi(part[1])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid offset %q: %w", offset, err)
+		}
+		n *= sign
+		switch part[2] {
+		case "w":
+			t = t.AddDate(0, 0, 7*n)
+		case "d":
+			t = t.AddDate(0, 0, n)
+		case "h":
+			t = t.Add(time.Duration(n) * time.Hour)
+		case "m":
+			t = t.Add(time.Duration(n) * time.Minute)
+		case "s":
+			t = t.Add(time.Duration(n) * time.Second)
+		}
+	}
+	return t, nil
+}
+
+// parseRelative reads "in 3 days" and "2 hours ago", reporting false for other text
+func parseRelative(text string, now time.Time) (time.Time, bool, error) {
+	words := strings.Fields(text)
+	sign := 1
+	switch {
+	case len(words) == 3 && words[0] == "in":
+		words = words[1:]
+	case len(words) == 3 && words[2] == "ago":
+		sign = -1
+		words = words[:2]
+	default:
+		return time.Time{}, false, nil
+	}
+
+	n, err := strconv.Atoi(words[0])
+	unit, ok := unitNames[words[1]]
+	if err != nil || !ok {
+		return time.Time{}, false, nil
+	}
+	t, err := applyOffset(now, sign, strconv.Itoa(n)+unit)
+	return t, true, err
+}
+
+// parseDayAndClock reads an optional day and an optional time of day, in either order
+func parseDayAndClock(words []string, now time.Time) (time.Time, bool) {
+	// "9 am" is one time, "at" only reads well
+	var tokens []string
+	for _, word := range words {
+		switch {
+		case word == "at":
+		case (word == "am" || word == "pm") && len(tokens) > 0:
+			tokens[len(tokens)-1] += word
+		default:
+			tokens = append(tokens, word)
+		}
+	}
+	if len(tokens) == 0 {
+		return time.Time{}, false
+	}
+
+	var clock time.Duration
+	hasClock := false
+	if c, ok := parseClock(tokens[len(tokens)-1]); ok {
+		clock, hasClock = c, true
+		tokens = tokens[:len(tokens)-1]
+	} else if c, ok := parseClock(tokens[0]); ok {
+		clock, hasClock = c, true
+		tokens = tokens[1:]
+	}
+
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if len(tokens) > 0 {
+		d, ok := parseDay(tokens, day)
+		if !ok {
+			return time.Time{}, false
+		}
+		day = d
+	} else if !hasClock {
+		return time.Time{}, false
+	}
+
+	// Build the wall clock time directly, adding the duration would shift it across DST changes
+	hour, minute, second := int(clock/time.Hour), int(clock%time.Hour/time.Minute), int(clock%time.Minute/time.Second)
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, second, 0, time.Local), true
+}
+
+// parseClock reads a time of day such as "18:30", "9am", "9:30pm", "noon" or "midnight"
+func parseClock(token string) (time.Duration, bool) {
+	switch token {
+	case "noon":
+		return 12 * time.Hour, true
+	case "midnight":
+		return 0, true
+	}
+
+	m := clockPattern.FindStringSubmatch(token)
+	// A bare number is not a time, "mon 9" is ambiguous
+	if m == nil || (m[2] == "" && m[4] == "") {
+		return 0, false
+	}
+	hour, _ := strconv.Atoi(m[1])
+	minute, _ := strconv.Atoi(m[2])
+	second, _ := strconv.Atoi(m[3])
+
+	if m[4] != "" {
+		if hour < 1 || hour > 12 {
+			return 0, false
+		}
+		hour %= 12
+		if m[4] == "pm" {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 || second > 59 {
+		return 0, false
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute + time.Duration(second)*time.Second, true
+}
+
+// parseDay reads the day part of an input relative to today, which is at midnight
+func parseDay(tokens []string, today time.Time) (time.Time, bool) {
+	if len(tokens) == 2 {
+		day, ok := weekdayNames[tokens[1]]
+		if !ok {
+			return time.Time{}, false
+		}
+		ahead := (int(day) - int(today.Weekday()) + 7) % 7
+		switch tokens[0] {
+		case "this":
+			return today.AddDate(0, 0, ahead), true
+		case "next":
+			if ahead == 0 {
+				ahead = 7
+			}
+			return today.AddDate(0, 0, ahead), true
+		case "last":
+			behind := (int(today.Weekday()) - int(day) + 7) % 7
+			if behind == 0 {
+				behind = 7
+			}
+			return today.AddDate(0, 0, -behind), true
+		}
+		return time.Time{}, false
+	}
+	if len(tokens) != 1 {
+		return time.Time{}, false
+	}
+
+	switch tokens[0] {
+	case "today":
+		return today, true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true
+	}
+	if day, ok := weekdayNames[tokens[0]]; ok {
+		return today.AddDate(0, 0, (int(day)-int(today.Weekday())+7)%7), true
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, tokens[0], time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,76 @@
+package timeparse
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	// 2024-05-02 is a Thursday
+	now := time.Date(2024, 5, 2, 10, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "2024-05-01 18:30:15", want: "2024-05-01 18:30:15"},
+		{input: "2024-05-01 18:30", want: "2024-05-01 18:30:00"},
+		{input: "2024-05-01", want: "2024-05-01 00:00:00"},
+		{input: "01-05-2024", want: "2024-05-01 00:00:00"},
+		{input: "2024-05-01T18:30:00", want: "2024-05-01 18:30:00"},
+		{input: "2024-05-01 9:30pm", want: "2024-05-01 21:30:00"},
+		{input: "now", want: "2024-05-02 10:30:00"},
+		{input: "  Today ", want: "2024-05-02 00:00:00"},
+		{input: "yesterday 18:30", want: "2024-05-01 18:30:00"},
+		{input: "tomorrow at noon", want: "2024-05-03 12:00:00"},
+		{input: "9am tomorrow", want: "2024-05-03 09:00:00"},
+		{input: "18:00", want: "2024-05-02 18:00:00"},
+		{input: "12am", want: "2024-05-02 00:00:00"},
+		{input: "+15", want: "2024-05-02 10:45:00"},
+		{input: "-90", want: "2024-05-02 09:00:00"},
+		{input: "+2h15m", want: "2024-05-02 12:45:00"},
+		{input: "-1d", want: "2024-05-01 10:30:00"},
+		{input: "+1w2d", want: "2024-05-11 10:30:00"},
+		{input: "in 3 days", want: "2024-05-05 10:30:00"},
+		{input: "2 hours ago", want: "2024-05-02 08:30:00"},
+		{input: "mon 9am", want: "2024-05-06 09:00:00"},
+		{input: "thu 9 am", want: "2024-05-02 09:00:00"},
+		{input: "next thursday", want: "2024-05-09 00:00:00"},
+		{input: "last friday", want: "2024-04-26 00:00:00"},
+		{input: "last thu 18:30", want: "2024-04-25 18:30:00"},
+		{input: "", wantErr: true},
+		{input: "soon", wantErr: true},
+		{input: "+2x", wantErr: true},
+		{input: "mon 9", wantErr: true},
+		{input: "13pm", wantErr: true},
+		{input: "25:00", wantErr: true},
+		{input: "2024-13-01", wantErr: true},
+		{input: "next week", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Parse(tt.input, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && Format(got) != tt.want {
+				t.Errorf("Parse() = %s, want %s", Format(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseZoned(t *testing.T) {
+	want := time.Date(2024, 5, 1, 18, 30, 0, 0, time.UTC)
+	for _, input := range []string{"2024-05-01T18:30:00Z", "2024-05-01T20:30:00+02:00", "2024-05-01T18:30Z"} {
+		got, err := Parse(input, time.Now())
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", input, err)
+		}
+		if !got.Equal(want) || got.Location() != time.Local {
+			t.Errorf("Parse(%q) = %v, want %v in local time", input, got, want)
+		}
+	}
+}